@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command func(args []string, stdin io.Reader, stdout io.Writer) error
+
+var registry = map[string]command{
+	"export":     Export,
+	"simulate":   Simulate,
+	"test-flows": TestFlows,
+}
+
+func Exist(name string) bool {
+	_, exist := registry[name]
+	return exist
+}
+
+func Run(name string, args []string) error {
+	run, exist := registry[name]
+	if !exist {
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	return run(args, os.Stdin, os.Stdout)
+}
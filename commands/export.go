@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"bot-routing-engine/controllers/services"
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories"
+	"bot-routing-engine/utils/graph"
+	"bot-routing-engine/utils/wording"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+func Export(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	file := flags.String("file", "", "layer JSON file to export")
+	name := flags.String("name", "layer", "layer name to export when no file is given")
+	version := flags.Int("version", 0, "layer version to export, the active layer when zero")
+	format := flags.String("format", graph.FormatDOT, "output format: dot or mermaid")
+	locale := flags.String("locale", wording.FallbackLocale(), "locale used for node labels")
+	out := flags.String("out", "", "output file, stdout when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	layer, err := loadLayer(*file, *name, *version)
+	if err != nil {
+		return err
+	}
+
+	output, err := graph.Render(layer, *format, *locale)
+	if err != nil {
+		return err
+	}
+
+	if len(*out) > 0 {
+		return ioutil.WriteFile(*out, []byte(output), 0644)
+	}
+
+	_, err = fmt.Fprint(stdout, output)
+	return err
+}
+
+func loadLayer(file string, name string, version int) (viewmodel.Layer, error) {
+	layerService := services.NewLayerService(nil, repositories.NewLayerRepository(), nil)
+
+	if len(file) > 0 {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return viewmodel.Layer{}, err
+		}
+		return layerService.ParseLayer(content)
+	}
+
+	if version > 0 {
+		return layerService.GetLayerVersion(name, version)
+	}
+
+	return layerService.ActiveLayer(name)
+}
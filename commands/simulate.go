@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"bot-routing-engine/repositories/memory"
+	"bot-routing-engine/simulator"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const simulateHelp = `Type a customer message and press enter. Commands:
+  :postback <option_id>  tap a button carrying the option id
+  :wait <duration>       advance the clock, e.g. :wait 15m, and run idle timers
+  :clock <time>          set the clock, e.g. :clock 2021-06-01 20:00
+  :state                 print the bot session
+  :reset                 forget the bot session
+  :help                  print this help
+  :quit                  exit
+`
+
+func Simulate(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	config := simulatorFlags(flags)
+	file := flags.String("file", "", "layer JSON file to simulate")
+	name := flags.String("name", "layer", "active layer name to simulate when no file is given")
+	version := flags.Int("version", 0, "layer version to simulate, the active layer when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	layer, err := loadLayer(*file, *name, *version)
+	if err != nil {
+		return err
+	}
+
+	h, err := simulator.New(layer, *config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Simulating at %s\n", h.Now().Format(simulator.TimeLayout))
+	fmt.Fprint(stdout, simulateHelp)
+
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, ":") {
+			events, err := h.Send(line, nil)
+			printEvents(stdout, events)
+			if err != nil {
+				fmt.Fprintf(stdout, "error: %s\n", err.Error())
+			}
+			printState(stdout, h)
+			continue
+		}
+
+		command, argument := line, ""
+		if index := strings.Index(line, " "); index > 0 {
+			command, argument = line[:index], strings.TrimSpace(line[index+1:])
+		}
+
+		switch command {
+		case ":quit", ":exit":
+			return nil
+		case ":help":
+			fmt.Fprint(stdout, simulateHelp)
+		case ":state":
+			printState(stdout, h)
+		case ":reset":
+			if err := h.Reset(); err != nil {
+				fmt.Fprintf(stdout, "error: %s\n", err.Error())
+			}
+		case ":clock":
+			if err := h.SetClock(argument); err != nil {
+				fmt.Fprintf(stdout, "error: %s\n", err.Error())
+				continue
+			}
+			fmt.Fprintf(stdout, "clock: %s\n", h.Now().Format(simulator.TimeLayout))
+		case ":wait":
+			duration, err := time.ParseDuration(argument)
+			if err != nil {
+				fmt.Fprintf(stdout, "error: %s\n", err.Error())
+				continue
+			}
+			printEvents(stdout, h.Advance(duration))
+			fmt.Fprintf(stdout, "clock: %s\n", h.Now().Format(simulator.TimeLayout))
+			printState(stdout, h)
+		case ":postback":
+			events, err := h.Send(argument, map[string]interface{}{"option_id": argument})
+			printEvents(stdout, events)
+			if err != nil {
+				fmt.Fprintf(stdout, "error: %s\n", err.Error())
+			}
+			printState(stdout, h)
+		default:
+			fmt.Fprintf(stdout, "unknown command %s, type :help\n", command)
+		}
+	}
+}
+
+func simulatorFlags(flags *flag.FlagSet) *simulator.Config {
+	config := new(simulator.Config)
+	flags.StringVar(&config.Clock, "clock", "", "simulated clock as \"2006-01-02 15:04\" in TIMEZONE, now when empty")
+	flags.StringVar(&config.Source, "source", "qiscus", "channel source, e.g. wa, line, telegram, qiscus")
+	flags.StringVar(&config.Channel, "channel", "simulator", "channel name")
+	flags.IntVar(&config.ChannelID, "channel-id", 0, "channel id used for locale and layer lookups")
+	flags.StringVar(&config.CustomerName, "customer", "Customer", "customer name")
+	flags.StringVar(&config.CustomerEmail, "email", "customer@example.com", "customer email")
+	flags.StringVar(&config.OfficeHours, "office-hours", "", "office hours as 08:00-17:00, \"closed\" for always offline, always open when empty")
+
+	return config
+}
+
+func printEvents(stdout io.Writer, events []memory.Event) {
+	for _, event := range events {
+		switch event.Kind {
+		case memory.EventMessage:
+			fmt.Fprintf(stdout, "bot: %s\n", strings.ReplaceAll(event.Text, "\n", "\n     "))
+		case memory.EventPayload:
+			payload, _ := json.Marshal(event.Data)
+			if len(event.Text) > 0 {
+				fmt.Fprintf(stdout, "bot: %s\n", strings.ReplaceAll(event.Text, "\n", "\n     "))
+			}
+			fmt.Fprintf(stdout, "[%s] %s\n", event.Type, payload)
+		case memory.EventTag:
+			fmt.Fprintf(stdout, "[tag] %s\n", event.Text)
+		case memory.EventUserInfo:
+			fmt.Fprintf(stdout, "[info] %s = %s\n", event.Type, event.Text)
+		case memory.EventHandover:
+			if len(event.Text) > 0 {
+				fmt.Fprintf(stdout, "[handover] division %s\n", event.Text)
+			} else {
+				fmt.Fprintln(stdout, "[handover]")
+			}
+		case memory.EventResolve:
+			fmt.Fprintln(stdout, "[resolve]")
+		}
+	}
+}
+
+func printState(stdout io.Writer, h *simulator.Simulator) {
+	session, exist := h.Session()
+	if !exist {
+		fmt.Fprintln(stdout, "state: no session")
+		return
+	}
+
+	var variables []string
+	for name, value := range session.Variables {
+		variables = append(variables, name+"="+value)
+	}
+	sort.Strings(variables)
+
+	fmt.Fprintf(stdout, "state: %s", session.Layer)
+	if len(session.Locale) > 0 {
+		fmt.Fprintf(stdout, " locale=%s", session.Locale)
+	}
+	if session.Page > 0 {
+		fmt.Fprintf(stdout, " page=%d", session.Page+1)
+	}
+	if len(variables) > 0 {
+		fmt.Fprintf(stdout, " vars: %s", strings.Join(variables, ", "))
+	}
+	fmt.Fprintln(stdout)
+}
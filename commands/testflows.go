@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/simulator"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultTestDir = "./layer/tests"
+
+func TestFlows(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("test-flows", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	layerFile := flags.String("layer", "", "layer JSON file, overrides the layer referenced by each spec")
+	verbose := flags.Bool("v", false, "print passing tests as well")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultTestDir}
+	}
+
+	files, err := specFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	passed, failed := 0, 0
+	report := func(source string, results []viewmodel.FlowTestResult) {
+		for _, result := range results {
+			if result.Passed {
+				passed++
+				if *verbose {
+					fmt.Fprintf(stdout, "PASS %s\n", result.Name)
+				}
+				continue
+			}
+
+			failed++
+			fmt.Fprintf(stdout, "FAIL %s (%s)\n", result.Name, source)
+			for _, failure := range result.Failures {
+				fmt.Fprintln(stdout, simulator.PrefixLines("    ", failure))
+			}
+		}
+	}
+
+	if len(*layerFile) > 0 {
+		layer, err := loadLayer(*layerFile, "", 0)
+		if err != nil {
+			return err
+		}
+		report(*layerFile, simulator.RunTests(layer, layer.Tests))
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		suite, err := simulator.ParseSuite(file, content)
+		if err != nil {
+			return err
+		}
+
+		layerPath := *layerFile
+		if len(layerPath) == 0 {
+			if len(suite.Layer) == 0 {
+				return fmt.Errorf("%s: no layer given, set \"layer\" in the spec or pass -layer", file)
+			}
+			layerPath = suite.Layer
+			if !filepath.IsAbs(layerPath) {
+				layerPath = filepath.Join(filepath.Dir(file), layerPath)
+			}
+		}
+
+		layer, err := loadLayer(layerPath, "", 0)
+		if err != nil {
+			return err
+		}
+		report(file, simulator.RunTests(layer, suite.Tests))
+	}
+
+	fmt.Fprintf(stdout, "%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d flow tests failed", failed)
+	}
+
+	return nil
+}
+
+func specFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) && path == defaultTestDir {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isSpecFile(file) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func isSpecFile(file string) bool {
+	name := strings.ToLower(filepath.Base(file))
+
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".test.json")
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"bot-routing-engine/controllers/services"
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/utils/graph"
+	"bot-routing-engine/utils/wording"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type layerController struct {
+	layerService services.LayerService
+}
+
+func NewLayerController(layerService services.LayerService) *layerController {
+	return &layerController{layerService}
+}
+
+func (controller *layerController) Versions(ctx echo.Context) error {
+	versions, err := controller.layerService.Versions(ctx.Param("name"))
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, versions)
+}
+
+func (controller *layerController) Diff(ctx echo.Context) error {
+	from, err := strconv.Atoi(ctx.QueryParam("from"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: "invalid from version"})
+	}
+
+	to, err := strconv.Atoi(ctx.QueryParam("to"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: "invalid to version"})
+	}
+
+	diffs, err := controller.layerService.DiffVersions(ctx.Param("name"), from, to)
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, diffs)
+}
+
+func (controller *layerController) Activate(ctx echo.Context) error {
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: "invalid version"})
+	}
+
+	activated, err := controller.layerService.ActivateVersion(ctx.Param("name"), version)
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, activated)
+}
+
+func (controller *layerController) Graph(ctx echo.Context) error {
+	var layer viewmodel.Layer
+	var err error
+	if versionParam := ctx.QueryParam("version"); len(versionParam) > 0 {
+		version, convErr := strconv.Atoi(versionParam)
+		if convErr != nil {
+			return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: "invalid version"})
+		}
+		layer, err = controller.layerService.GetLayerVersion(ctx.Param("name"), version)
+	} else {
+		layer, err = controller.layerService.ActiveLayer(ctx.Param("name"))
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	locale := ctx.QueryParam("locale")
+	if len(locale) == 0 {
+		locale = wording.FallbackLocale()
+	}
+
+	output, err := graph.Render(layer, ctx.QueryParam("format"), locale)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	return ctx.String(http.StatusOK, output)
+}
@@ -3,8 +3,8 @@ package controllers
 import (
 	"bot-routing-engine/controllers/services"
 	"bot-routing-engine/entities/viewmodel"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
@@ -26,55 +26,25 @@ func (controller *messageController) MessageReceived(ctx echo.Context) error {
 		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
 	}
 
-	drafts, err := controller.messageService.Determine(reqBody)
+	input := reqBody.(*viewmodel.WebhookRequest)
+	unlock, err := controller.roomService.Lock(input.Payload.Room.ID)
 	if err != nil {
 		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
 	}
+	defer unlock()
 
-	for _, draft := range drafts {
-		if !draft.Layer.Handover && !draft.Layer.Resolve {
-			err = controller.roomService.SendBotMessage(draft.Room.Payload.Room.ID, draft.Message)
-			if err != nil {
-				return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-			}
-		}
-
-		if draft.Layer.Resolve && !draft.Layer.Handover {
-			if len(draft.Message) > 0 {
-				err = controller.roomService.SendBotMessage(draft.Room.Payload.Room.ID, draft.Message)
-				if err != nil {
-					return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-				}
-			}
-			qismoRoomInfo, err := controller.roomService.QismoRoomInfo(draft.Room.Payload.Room.ID)
-			if err != nil {
-				return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-			}
-
-			err = controller.roomService.Resolve(draft.Room.Payload.Room.ID, strconv.Itoa(qismoRoomInfo.Data.CustomerRoom.ID))
-			if err != nil {
-				return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-			}
-		}
-
-		if draft.Layer.Handover {
-			if len(draft.Message) > 0 {
-				err = controller.roomService.SendBotMessage(draft.Room.Payload.Room.ID, draft.Message)
-				if err != nil {
-					return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-				}
-			}
-
-			if len(draft.Layer.Division) > 0 {
-				err = controller.roomService.HandoverWithDivision(draft.Room.Payload.Room.ID, draft.Layer.Division)
-			} else {
-				err = controller.roomService.Handover(draft.Room.Payload.Room.ID)
-			}
+	// Retried webhooks are acknowledged without being processed again.
+	drafts, err := controller.messageService.Determine(reqBody)
+	if errors.Is(err, services.ErrMessageSeen) {
+		return ctx.String(http.StatusOK, "")
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
 
-			if err != nil {
-				return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
-			}
-		}
+	err = controller.roomService.Dispatch(drafts)
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
 	}
 
 	return ctx.String(http.StatusOK, "")
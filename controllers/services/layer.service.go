@@ -2,25 +2,95 @@ package services
 
 import (
 	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories"
+	"bot-routing-engine/utils/agent"
+	"bot-routing-engine/utils/condition"
+	"bot-routing-engine/utils/flow"
+	"bot-routing-engine/utils/input"
+	"bot-routing-engine/utils/jsonpath"
+	"bot-routing-engine/utils/menu"
+	"bot-routing-engine/utils/render"
+	"bot-routing-engine/utils/rich"
+	"bot-routing-engine/utils/wording"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type LayerService interface {
 	GetLayer(source int) (viewmodel.Layer, error)
-	DetermineLayer(state string, states []int, layer viewmodel.Layer) (viewmodel.Layer, error)
+	ParseLayer(content []byte) (viewmodel.Layer, error)
+	ValidateLayer(layer viewmodel.Layer) ([]viewmodel.LayerError, error)
+	ResolveState(state string, layer viewmodel.Layer) (string, bool)
+	DetermineLayer(state string, session *viewmodel.BotSession, layer viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error)
+	DeterminePostback(optionID string, session *viewmodel.BotSession, layer viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error)
+	EnterLayer(layer viewmodel.Layer, session *viewmodel.BotSession, root viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error)
+	PreviousLayer(current string, root viewmodel.Layer, session *viewmodel.BotSession, flowContext viewmodel.FlowContext) (viewmodel.Layer, error)
+	MatchCommand(state string, layer viewmodel.Layer) (viewmodel.Command, bool)
+	Menu(layer viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) string
+	DynamicLayer(layer viewmodel.Layer, session viewmodel.BotSession) viewmodel.Layer
+	ActiveLayer(name string) (viewmodel.Layer, error)
+	Versions(name string) ([]viewmodel.LayerVersion, error)
+	GetLayerVersion(name string, version int) (viewmodel.Layer, error)
+	PublishLayer(name string, content []byte, uploader string) (viewmodel.LayerVersion, error)
+	ActivateVersion(name string, version int) (viewmodel.LayerVersion, error)
+	DiffVersions(name string, from int, to int) ([]viewmodel.LayerDiff, error)
+}
+
+const maxFlowJumps = 50
+
+var (
+	ErrFlowLoop       = errors.New("flow loop detected")
+	ErrOptionNotFound = errors.New("option not found on current node")
+)
+
+type ReplyError struct {
+	Text viewmodel.Text
+}
+
+func (e *ReplyError) Error() string {
+	return e.Text.Get(wording.FallbackLocale(), "")
 }
 
 type layerService struct {
+	multichannelRepository repositories.MultichannelRepository
+	layerRepository        repositories.LayerRepository
+	actionRepository       repositories.ActionRepository
+	client                 *http.Client
+	cacheTTL               time.Duration
+	cache                  map[int]cachedLayer
+	mu                     sync.RWMutex
 }
 
-func NewLayerService() *layerService {
-	return &layerService{}
+type cachedLayer struct {
+	layer        viewmodel.Layer
+	location     string
+	modTime      time.Time
+	size         int64
+	etag         string
+	lastModified string
+	checkedAt    time.Time
+}
+
+func NewLayerService(multichannelRepository repositories.MultichannelRepository, layerRepository repositories.LayerRepository, actionRepository repositories.ActionRepository) *layerService {
+	cacheTTL, _ := strconv.Atoi(os.Getenv("LAYER_CACHE_TTL"))
+
+	return &layerService{
+		multichannelRepository: multichannelRepository,
+		layerRepository:        layerRepository,
+		actionRepository:       actionRepository,
+		client:                 &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:               time.Duration(cacheTTL) * time.Second,
+		cache:                  make(map[int]cachedLayer),
+	}
 }
 
 func (ls *layerService) GetLayer(source int) (viewmodel.Layer, error) {
@@ -29,39 +99,120 @@ func (ls *layerService) GetLayer(source int) (viewmodel.Layer, error) {
 		return ls.getLayerFromURL(source, layerURL)
 	}
 
-	filePath := fmt.Sprintf("./layer/%d.json", source)
+	filePath := ls.layerRepository.ActivePath(strconv.Itoa(source))
 
 	if os.Getenv("ALL_IN_ONE_JSON_ROUTE") == "true" {
 		layerURL, layerURLExist = os.LookupEnv("LAYER_URL")
 		if layerURLExist {
 			return ls.getLayerFromURL(source, layerURL)
 		}
-		filePath = "./layer/layer.json"
+		filePath = ls.layerRepository.ActivePath("layer")
+	}
+
+	return ls.getLayerFromFile(source, filePath)
+}
+
+func (s *layerService) cached(source int, location string) (cachedLayer, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exist := s.cache[source]
+	if !exist || entry.location != location {
+		return cachedLayer{}, false
 	}
 
-	jsonFile, err := os.Open(filePath)
+	return entry, true
+}
+
+func (s *layerService) store(source int, entry cachedLayer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache[source] = entry
+}
+
+func (s *layerService) getLayerFromFile(source int, filePath string) (viewmodel.Layer, error) {
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return viewmodel.Layer{}, err
 	}
 
-	defer jsonFile.Close()
+	entry, exist := s.cached(source, filePath)
+	if exist && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.layer, nil
+	}
 
-	byteValue, _ := ioutil.ReadAll(jsonFile)
+	byteValue, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return viewmodel.Layer{}, err
+	}
 
-	var layer viewmodel.Layer
-	json.Unmarshal(byteValue, &layer)
+	layer, err := s.ParseLayer(byteValue)
+	if err != nil {
+		if exist {
+			return entry.layer, nil
+		}
+		return viewmodel.Layer{}, err
+	}
+
+	s.store(source, cachedLayer{
+		layer:    layer,
+		location: filePath,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+	})
 
 	return layer, nil
 }
 
 func (s *layerService) getLayerFromURL(source int, url string) (viewmodel.Layer, error) {
-	resp, err := http.Get(url)
+	entry, exist := s.cached(source, url)
+	if exist && time.Since(entry.checkedAt) < s.cacheTTL {
+		return entry.layer, nil
+	}
+
+	layer, err := s.fetchLayer(url, &entry)
+	if err != nil {
+		if exist {
+			return entry.layer, nil
+		}
+		return viewmodel.Layer{}, err
+	}
+
+	entry.layer = layer
+	entry.location = url
+	entry.checkedAt = time.Now()
+	s.store(source, entry)
+
+	return layer, nil
+}
+
+func (s *layerService) fetchLayer(url string, entry *cachedLayer) (viewmodel.Layer, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return viewmodel.Layer{}, err
+	}
+
+	if len(entry.location) > 0 {
+		if len(entry.etag) > 0 {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if len(entry.lastModified) > 0 {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return viewmodel.Layer{}, err
 	}
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && len(entry.location) > 0 {
+		return entry.layer, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return viewmodel.Layer{}, fmt.Errorf("unexpected http GET status: %s", resp.Status)
 	}
@@ -71,47 +222,933 @@ func (s *layerService) getLayerFromURL(source int, url string) (viewmodel.Layer,
 		return viewmodel.Layer{}, err
 	}
 
+	layer, err := s.ParseLayer(body)
+	if err != nil {
+		return viewmodel.Layer{}, err
+	}
+
+	entry.etag = resp.Header.Get("ETag")
+	entry.lastModified = resp.Header.Get("Last-Modified")
+
+	return layer, nil
+}
+
+func (s *layerService) ParseLayer(content []byte) (viewmodel.Layer, error) {
 	var layer viewmodel.Layer
-	json.Unmarshal(body, &layer)
+	if err := json.Unmarshal(content, &layer); err != nil {
+		return viewmodel.Layer{}, err
+	}
+	flow.AssignIDs(&layer)
 
 	return layer, nil
 }
 
-func (s *layerService) getLatestLayer(states []int, layer viewmodel.Layer) viewmodel.Layer {
-	for _, state := range states {
-		layer = layer.Options[state-1]
+func (s *layerService) ActiveLayer(name string) (viewmodel.Layer, error) {
+	content, err := ioutil.ReadFile(s.layerRepository.ActivePath(name))
+	if err != nil {
+		return viewmodel.Layer{}, err
+	}
+
+	return s.ParseLayer(content)
+}
+
+func (s *layerService) Versions(name string) ([]viewmodel.LayerVersion, error) {
+	return s.layerRepository.Versions(name)
+}
+
+func (s *layerService) GetLayerVersion(name string, version int) (viewmodel.Layer, error) {
+	content, err := s.layerRepository.ReadVersion(name, version)
+	if err != nil {
+		return viewmodel.Layer{}, err
+	}
+
+	return s.ParseLayer(content)
+}
+
+func (s *layerService) PublishLayer(name string, content []byte, uploader string) (viewmodel.LayerVersion, error) {
+	version, err := s.layerRepository.CreateVersion(name, content, uploader)
+	if err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	return s.layerRepository.Activate(name, version.Version)
+}
+
+func (s *layerService) ActivateVersion(name string, version int) (viewmodel.LayerVersion, error) {
+	return s.layerRepository.Activate(name, version)
+}
+
+func (s *layerService) DiffVersions(name string, from int, to int) ([]viewmodel.LayerDiff, error) {
+	fromLayer, err := s.GetLayerVersion(name, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toLayer, err := s.GetLayerVersion(name, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromNodes := flow.Index(fromLayer)
+	toNodes := flow.Index(toLayer)
+
+	diffs := []viewmodel.LayerDiff{}
+	for _, ID := range flow.IDs(fromLayer) {
+		toNode, exist := toNodes[ID]
+		if !exist {
+			diffs = append(diffs, viewmodel.LayerDiff{ID: ID, Change: "removed"})
+			continue
+		}
+
+		if fields := s.changedFields(fromNodes[ID], toNode); len(fields) > 0 {
+			diffs = append(diffs, viewmodel.LayerDiff{ID: ID, Change: "changed", Fields: fields})
+		}
+	}
+
+	for _, ID := range flow.IDs(toLayer) {
+		if _, exist := fromNodes[ID]; !exist {
+			diffs = append(diffs, viewmodel.LayerDiff{ID: ID, Change: "added"})
+		}
+	}
+
+	return diffs, nil
+}
+
+func (s *layerService) changedFields(from viewmodel.Layer, to viewmodel.Layer) []string {
+	fromFields := s.nodeFields(from)
+	toFields := s.nodeFields(to)
+
+	var fields []string
+	for key, value := range toFields {
+		if string(fromFields[key]) != string(value) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range fromFields {
+		if _, exist := toFields[key]; !exist {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func (s *layerService) nodeFields(layer viewmodel.Layer) map[string]json.RawMessage {
+	var optionIDs []string
+	for _, option := range layer.Options {
+		optionIDs = append(optionIDs, option.ID)
+	}
+	layer.Options = nil
+	layer.Subflows = nil
+
+	content, _ := json.Marshal(layer)
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(content, &fields)
+
+	fields["options"], _ = json.Marshal(optionIDs)
+
+	return fields
+}
+
+func (s *layerService) ValidateLayer(layer viewmodel.Layer) ([]viewmodel.LayerError, error) {
+	var divisions []viewmodel.Division
+	if s.usesDivision(layer) {
+		resp, err := s.multichannelRepository.GetAllDivisions()
+		if err != nil {
+			return nil, err
+		}
+		divisions = resp.Data
+	}
+
+	var layerErrors []viewmodel.LayerError
+	seenIDs := make(map[string]string)
+	s.validateNode("$", layer, layer, divisions, seenIDs, &layerErrors)
+	for _, name := range flow.SubflowNames(layer) {
+		s.validateNode(fmt.Sprintf("$.subflows.%s", name), layer.Subflows[name], layer, divisions, seenIDs, &layerErrors)
+	}
+	if err := menu.CheckFormat(layer.MenuFormat); err != nil {
+		layerErrors = append(layerErrors, viewmodel.LayerError{Path: "$.menu_format", Reason: err.Error()})
+	}
+	for i, command := range layer.Commands {
+		for _, reason := range s.validateCommand(command, layer, divisions) {
+			layerErrors = append(layerErrors, viewmodel.LayerError{Path: fmt.Sprintf("$.commands[%d]", i), Reason: reason})
+		}
+	}
+
+	return layerErrors, nil
+}
+
+func (s *layerService) usesDivision(layer viewmodel.Layer) bool {
+	for _, command := range layer.Commands {
+		if len(command.Division) > 0 {
+			return true
+		}
+	}
+
+	used := false
+	flow.Walk(layer, func(node viewmodel.Layer) {
+		used = used || len(node.Division) > 0 || (node.InvalidLimit != nil && len(node.InvalidLimit.Division) > 0)
+	})
+
+	return used
+}
+
+func (s *layerService) validateCommand(command viewmodel.Command, root viewmodel.Layer, divisions []viewmodel.Division) []string {
+	var reasons []string
+
+	if len(command.Keywords) == 0 {
+		reasons = append(reasons, "command requires at least one keyword")
+	}
+
+	switch command.Action {
+	case viewmodel.CommandRoot, viewmodel.CommandBack, viewmodel.CommandHandover, viewmodel.CommandResolve, viewmodel.CommandRepeat, viewmodel.CommandGoto:
+	default:
+		reasons = append(reasons, fmt.Sprintf("unknown command action %q", command.Action))
+	}
+
+	return append(reasons, s.validateTarget(command, root, divisions)...)
+}
+
+func (s *layerService) validateLimit(limit viewmodel.InvalidLimit, root viewmodel.Layer, divisions []viewmodel.Division) []string {
+	var reasons []string
+
+	if limit.Attempts <= 0 {
+		reasons = append(reasons, "invalid limit attempts must be greater than zero")
+	}
+
+	switch limit.Action {
+	case viewmodel.CommandHandover, viewmodel.CommandResolve, viewmodel.CommandGoto:
+	default:
+		reasons = append(reasons, fmt.Sprintf("unknown invalid limit action %q", limit.Action))
+	}
+
+	return append(reasons, s.validateTarget(limitCommand(limit), root, divisions)...)
+}
+
+func (s *layerService) validateTarget(command viewmodel.Command, root viewmodel.Layer, divisions []viewmodel.Division) []string {
+	var reasons []string
+
+	if len(command.Division) > 0 && agent.GetDivisionByName(command.Division, divisions).ID == 0 {
+		reasons = append(reasons, fmt.Sprintf("division %q does not exist", command.Division))
+	}
+
+	if command.Action == viewmodel.CommandGoto {
+		if _, err := s.gotoTarget(viewmodel.Layer{Goto: command.Goto}, root); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+	}
+
+	return reasons
+}
+
+func limitCommand(limit viewmodel.InvalidLimit) viewmodel.Command {
+	return viewmodel.Command{
+		Action:   limit.Action,
+		Message:  limit.Message,
+		Division: limit.Division,
+		Goto:     limit.Goto,
+	}
+}
+
+func (s *layerService) validateNode(path string, layer viewmodel.Layer, root viewmodel.Layer, divisions []viewmodel.Division, seenIDs map[string]string, layerErrors *[]viewmodel.LayerError) {
+	addError := func(reason string) {
+		*layerErrors = append(*layerErrors, viewmodel.LayerError{Path: path, Reason: reason})
+	}
+
+	if seenPath, seen := seenIDs[layer.ID]; seen {
+		addError(fmt.Sprintf("id %q is already used by %s", layer.ID, seenPath))
+	} else {
+		seenIDs[layer.ID] = path
+	}
+
+	if len(layer.Tests) > 0 && path != "$" {
+		addError("tests are only read from the root node")
+	}
+
+	if layer.Handover && layer.Resolve {
+		addError("node cannot be both handover and resolve")
+	}
+
+	terminal := layer.Handover || layer.Resolve || layer.Type == viewmodel.LayerTypeGoto
+	if layer.Input && len(layer.Options) == 0 {
+		addError("input node requires a follow-up option")
+	} else if !terminal && len(layer.Options) == 0 {
+		addError("options must not be empty on non-terminal node")
+	}
+
+	if layer.Input {
+		if err := input.CheckValidation(layer.Validation); err != nil {
+			addError(err.Error())
+		}
+	}
+
+	switch layer.Type {
+	case "":
+	case viewmodel.LayerTypeCondition:
+		if len(layer.Options) > 0 && len(layer.Options[len(layer.Options)-1].When) > 0 {
+			addError("condition node requires a fallback option without rules as its last option")
+		}
+	case viewmodel.LayerTypeHTTP:
+		if len(layer.Options) > 0 && len(layer.Options[len(layer.Options)-1].When) > 0 {
+			addError("http node requires a fallback option without rules as its last option")
+		}
+		if err := s.checkRequest(layer.Request); err != nil {
+			addError(err.Error())
+		}
+	case viewmodel.LayerTypeDynamic:
+		if err := s.checkSource(layer.Source); err != nil {
+			addError(err.Error())
+		} else if _, err := s.gotoTarget(viewmodel.Layer{Goto: layer.Source.Empty}, root); err != nil {
+			addError("dynamic source empty " + err.Error())
+		}
+		if len(layer.Options) > 1 {
+			addError("dynamic node takes a single follow-up option, the no items branch is set with source.empty")
+		}
+	case viewmodel.LayerTypeLanguage:
+		for _, option := range layer.Options {
+			if len(option.Locale) == 0 {
+				addError("language node options must declare a locale")
+				break
+			}
+		}
+	case viewmodel.LayerTypeGoto:
+		if _, err := s.gotoTarget(layer, root); err != nil {
+			addError(err.Error())
+		}
+		if !layer.Message.IsEmpty() {
+			addError("goto node cannot have a message, it is never sent, put it on the target node instead")
+		}
+	default:
+		addError(fmt.Sprintf("unknown node type %q", layer.Type))
+	}
+
+	if err := s.checkFlowLoop(layer, root, make(map[string]bool), make(map[string]bool)); err != nil {
+		addError(err.Error())
+	}
+
+	if err := rich.Check(layer); err != nil {
+		addError(err.Error())
+	}
+
+	if layer.InvalidLimit != nil {
+		for _, reason := range s.validateLimit(*layer.InvalidLimit, root, divisions) {
+			addError(reason)
+		}
+	}
+
+	for _, tag := range layer.Tags {
+		if len(strings.TrimSpace(tag)) == 0 {
+			addError("tags must not be empty")
+			break
+		}
+	}
+	for key := range layer.AdditionalInfo {
+		if len(strings.TrimSpace(key)) == 0 {
+			addError("additional info keys must not be empty")
+			break
+		}
+	}
+
+	if layer.Timeout != nil {
+		if layer.Timeout.ReminderAfter < 0 || layer.Timeout.ResolveAfter < 0 {
+			addError("timeout durations must not be negative")
+		} else if layer.Timeout.ReminderAfter > 0 && layer.Timeout.ResolveAfter > 0 && layer.Timeout.ResolveAfter <= layer.Timeout.ReminderAfter {
+			addError("timeout resolve_after must be greater than reminder_after")
+		}
+	}
+
+	for _, rule := range layer.When {
+		if err := condition.Check(rule); err != nil {
+			addError(err.Error())
+		}
+	}
+
+	if len(layer.Division) > 0 {
+		if agent.GetDivisionByName(layer.Division, divisions).ID == 0 {
+			addError(fmt.Sprintf("division %q does not exist", layer.Division))
+		}
+	}
+
+	for i, option := range layer.Options {
+		s.validateNode(fmt.Sprintf("%s.options[%d]", path, i), option, root, divisions, seenIDs, layerErrors)
 	}
-	return layer
 }
 
-func (s *layerService) DetermineLayer(state string, states []int, layer viewmodel.Layer) (viewmodel.Layer, error) {
-	if len(states) > 0 {
-		if prevLayerKeypad, prevLayerKeypadEnable := os.LookupEnv("RETURN_PREVIOUS_LAYER_KEYPAD"); prevLayerKeypadEnable &&
-			state == prevLayerKeypad {
-			removedLastState := states[:len(states)-1]
-			return s.getLatestLayer(removedLastState, layer), nil
+func (s *layerService) checkRequest(request *viewmodel.HTTPRequest) error {
+	if request == nil || len(request.URL) == 0 {
+		return fmt.Errorf("request requires a url")
+	}
+
+	switch strings.ToUpper(request.Method) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("unsupported request method %q", request.Method)
+	}
+
+	if request.Timeout < 0 {
+		return fmt.Errorf("request timeout must not be negative")
+	}
+
+	for variable, path := range request.Response {
+		if len(variable) == 0 || len(path) == 0 {
+			return fmt.Errorf("response mapping requires a variable and a path")
+		}
+	}
+
+	return nil
+}
+
+func (s *layerService) checkSource(source *viewmodel.DynamicSource) error {
+	if source == nil {
+		return fmt.Errorf("dynamic node requires a source")
+	}
+
+	if (source.Request == nil) == (len(source.Variable) == 0) {
+		return fmt.Errorf("dynamic source requires either a request or a variable")
+	}
+
+	if len(source.Save) == 0 {
+		return fmt.Errorf("dynamic source requires a variable to save the selection")
+	}
+
+	if len(source.Empty) == 0 {
+		return fmt.Errorf("dynamic source requires an empty target for when no items are listed")
+	}
+
+	if source.Request != nil {
+		return s.checkRequest(source.Request)
+	}
+
+	return nil
+}
+
+func (s *layerService) ResolveState(state string, layer viewmodel.Layer) (string, bool) {
+	var states []int
+	if err := json.Unmarshal([]byte(state), &states); err == nil {
+		if legacyLayer, ok := flow.FromPath(layer, states); ok {
+			return legacyLayer.ID, true
 		}
+		return layer.ID, true
+	}
+
+	if len(state) == 0 {
+		return layer.ID, true
+	}
 
-		layer = s.getLatestLayer(states, layer)
+	return state, false
+}
+
+func (s *layerService) getLatestLayer(current string, layer viewmodel.Layer) viewmodel.Layer {
+	if latest, ok := flow.Find(layer, current); ok {
+		return latest
+	}
+	return layer
+}
+
+func (s *layerService) DetermineLayer(state string, session *viewmodel.BotSession, layer viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	root := layer
+	current := session.Layer
+	if current != layer.ID {
+		layer = s.getLatestLayer(current, layer)
 		if layer.Handover || layer.Resolve {
 			return layer, nil
 		}
 	}
 
-	if layer.Input {
-		return layer.Options[0], nil
+	if layer.Input && len(layer.Options) > 0 {
+		if !input.Validate(state, layer.Validation) {
+			if !layer.RetryMessage.IsEmpty() {
+				return layer, &ReplyError{layer.RetryMessage}
+			}
+			return layer, &ReplyError{wording.Get(wording.InvalidInput)}
+		}
+
+		if len(layer.Variable) > 0 {
+			if session.Variables == nil {
+				session.Variables = make(map[string]string)
+			}
+			session.Variables[layer.Variable] = strings.TrimSpace(state)
+		}
+
+		return s.EnterLayer(layer.Options[0], session, root, flowContext)
+	}
+
+	options := s.DynamicLayer(layer, *session)
+	if s.isMoreOption(state, options, root, *session) {
+		session.Page = (session.Page + 1) % menu.Pages(options, root.MenuFormat)
+		return layer, nil
+	}
+
+	selected, ok := s.selectOption(state, options, root, *session)
+	if !ok {
+		return layer, &ReplyError{wording.Get(wording.InvalidOption)}
+	}
+
+	if layer.Type == viewmodel.LayerTypeDynamic {
+		return s.enterItem(selected, layer, options, session, root, flowContext)
+	}
+
+	return s.enterOption(selected, session, root, flowContext)
+}
+
+func (s *layerService) enterItem(selected viewmodel.Layer, layer viewmodel.Layer, options viewmodel.Layer, session *viewmodel.BotSession, root viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	for i, option := range options.Options {
+		if option.ID != selected.ID {
+			continue
+		}
+
+		item := session.Options.Items[i]
+		if session.Variables == nil {
+			session.Variables = make(map[string]string)
+		}
+		session.Variables[layer.Source.Save] = item.Value
+		session.Variables[layer.Source.Save+"_title"] = item.Title
+		session.Options = nil
+
+		return s.EnterLayer(layer.Options[0], session, root, flowContext)
+	}
+
+	return layer, &ReplyError{wording.Get(wording.InvalidOption)}
+}
+
+// DynamicLayer returns a dynamic node with its snapshotted items as options,
+// their IDs are the node ID followed by #n. Other nodes are returned as is.
+func (s *layerService) DynamicLayer(layer viewmodel.Layer, session viewmodel.BotSession) viewmodel.Layer {
+	if layer.Type != viewmodel.LayerTypeDynamic {
+		return layer
+	}
+
+	dynamic := layer
+	dynamic.Menu = true
+	dynamic.Options = nil
+	if session.Options == nil || session.Options.Node != layer.ID {
+		return dynamic
+	}
+
+	for i, item := range session.Options.Items {
+		dynamic.Options = append(dynamic.Options, viewmodel.Layer{
+			ID:    fmt.Sprintf("%s#%d", layer.ID, i+1),
+			Title: viewmodel.NewText(item.Title),
+		})
+	}
+
+	return dynamic
+}
+
+func (s *layerService) snapshotOptions(layer viewmodel.Layer, session *viewmodel.BotSession, flowContext viewmodel.FlowContext) {
+	source := layer.Source
+	if source == nil {
+		session.Options = &viewmodel.OptionSnapshot{Node: layer.ID}
+		return
+	}
+	values := s.templateValues(*session, flowContext)
+
+	var items []interface{}
+	if source.Request != nil {
+		response, err := s.actionRepository.Call(s.renderRequest(*source.Request, values, flowContext.Now))
+		if err == nil && response.Status >= 200 && response.Status < 300 {
+			items = s.listItems(response.Body, source.Items)
+		}
+	} else if value := session.Variables[source.Variable]; len(value) > 0 {
+		items = s.listItems([]byte(value), source.Items)
+		if items == nil {
+			for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+				if part = strings.TrimSpace(part); len(part) > 0 {
+					items = append(items, part)
+				}
+			}
+		}
+	}
+
+	titleTemplate := source.Title
+	if len(titleTemplate) == 0 {
+		titleTemplate = "{{item}}"
+	}
+	valueTemplate := source.Value
+	if len(valueTemplate) == 0 {
+		valueTemplate = titleTemplate
+	}
+
+	snapshot := viewmodel.OptionSnapshot{Node: layer.ID}
+	for _, item := range items {
+		itemValues := jsonpath.Flatten(item, "item")
+		for name, value := range values {
+			itemValues[name] = value
+		}
+
+		title := render.Render(titleTemplate, itemValues, flowContext.Now)
+		if len(strings.TrimSpace(title)) == 0 {
+			continue
+		}
+		snapshot.Items = append(snapshot.Items, viewmodel.SnapshotItem{
+			Title: title,
+			Value: render.Render(valueTemplate, itemValues, flowContext.Now),
+		})
+	}
+
+	session.Options = &snapshot
+}
+
+func (s *layerService) listItems(content []byte, path string) []interface{} {
+	var data interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil
+	}
+
+	found, exist := jsonpath.Find(data, path)
+	if !exist {
+		return nil
+	}
+	items, _ := found.([]interface{})
+
+	return items
+}
+
+func (s *layerService) DeterminePostback(optionID string, session *viewmodel.BotSession, layer viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	root := layer
+	layer = s.getLatestLayer(session.Layer, layer)
+	if layer.Input || layer.Handover || layer.Resolve {
+		return layer, ErrOptionNotFound
+	}
+
+	if layer.Type == viewmodel.LayerTypeDynamic {
+		options := s.DynamicLayer(layer, *session)
+		for _, option := range options.Options {
+			if option.ID == optionID {
+				return s.enterItem(option, layer, options, session, root, flowContext)
+			}
+		}
+
+		return layer, ErrOptionNotFound
+	}
+
+	for _, option := range layer.Options {
+		if option.ID == optionID {
+			return s.enterOption(option, session, root, flowContext)
+		}
+	}
+
+	return layer, ErrOptionNotFound
+}
+
+func (s *layerService) enterOption(selected viewmodel.Layer, session *viewmodel.BotSession, root viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	if len(selected.Locale) > 0 {
+		session.Locale = selected.Locale
+	}
+
+	return s.EnterLayer(selected, session, root, flowContext)
+}
+
+func (s *layerService) Menu(layer viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) string {
+	layer = s.DynamicLayer(layer, session)
+	if !menu.Enabled(layer, root.MenuFormat) {
+		return ""
+	}
+
+	page := session.Page
+	if page >= menu.Pages(layer, root.MenuFormat) {
+		page = 0
+	}
+
+	return menu.Render(layer, root.MenuFormat, page, session.Locale, wording.FallbackLocale())
+}
+
+func (s *layerService) isMoreOption(state string, layer viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) bool {
+	if !menu.Enabled(layer, root.MenuFormat) || !menu.Paginated(layer, root.MenuFormat) {
+		return false
+	}
+
+	option, ok := input.Number(state)
+	return ok && option == s.pageOptionCount(layer, root, session)+1
+}
+
+func (s *layerService) pageOptionCount(layer viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) int {
+	size := menu.PageSize(root.MenuFormat)
+	remaining := len(layer.Options) - session.Page*size
+	if remaining < size {
+		return remaining
+	}
+
+	return size
+}
+
+func (s *layerService) EnterLayer(layer viewmodel.Layer, session *viewmodel.BotSession, root viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	session.Page = 0
+	if flowContext.Attributes == nil {
+		flowContext.Attributes = make(map[string]string)
+	}
+	visited := make(map[string]bool)
+	for jumps := 0; ; jumps++ {
+		if visited[layer.ID] || jumps > maxFlowJumps {
+			return layer, fmt.Errorf("%w at node %q", ErrFlowLoop, layer.ID)
+		}
+		visited[layer.ID] = true
+
+		switch layer.Type {
+		case viewmodel.LayerTypeCondition:
+			next, matched := s.matchCondition(layer, session, flowContext)
+			if !matched {
+				return layer, nil
+			}
+			layer = next
+		case viewmodel.LayerTypeGoto:
+			next, err := s.gotoTarget(layer, root)
+			if err != nil {
+				return layer, err
+			}
+			layer = next
+		case viewmodel.LayerTypeHTTP:
+			s.callAction(layer, session, flowContext)
+			next, matched := s.matchCondition(layer, session, flowContext)
+			if !matched {
+				return layer, nil
+			}
+			layer = next
+		case viewmodel.LayerTypeDynamic:
+			s.snapshotOptions(layer, session, flowContext)
+			if len(session.Options.Items) > 0 || layer.Source == nil || len(layer.Source.Empty) == 0 {
+				return layer, nil
+			}
+			session.Options = nil
+			next, err := s.gotoTarget(viewmodel.Layer{Goto: layer.Source.Empty}, root)
+			if err != nil {
+				return layer, err
+			}
+			layer = next
+		default:
+			return layer, nil
+		}
+	}
+}
+
+func (s *layerService) gotoTarget(layer viewmodel.Layer, root viewmodel.Layer) (viewmodel.Layer, error) {
+	if layer.Goto == flow.RootID {
+		return root, nil
+	}
+
+	if subflow, exist := root.Subflows[layer.Goto]; exist {
+		return subflow, nil
+	}
+
+	if target, exist := flow.Find(root, layer.Goto); exist {
+		return target, nil
+	}
+
+	return layer, fmt.Errorf("goto target %q does not exist", layer.Goto)
+}
+
+// checkFlowLoop follows every node that EnterLayer passes through without
+// waiting for input, a path that comes back to one of its own nodes would fail
+// at runtime with ErrFlowLoop. Nodes in clean are known not to reach a loop.
+func (s *layerService) checkFlowLoop(layer viewmodel.Layer, root viewmodel.Layer, path map[string]bool, clean map[string]bool) error {
+	if clean[layer.ID] {
+		return nil
+	}
+	if path[layer.ID] {
+		return fmt.Errorf("flow loops back to node %q without waiting for input", layer.ID)
+	}
+
+	path[layer.ID] = true
+	for _, next := range s.passthroughTargets(layer, root) {
+		if err := s.checkFlowLoop(next, root, path, clean); err != nil {
+			return err
+		}
+	}
+	delete(path, layer.ID)
+	clean[layer.ID] = true
+
+	return nil
+}
+
+func (s *layerService) passthroughTargets(layer viewmodel.Layer, root viewmodel.Layer) []viewmodel.Layer {
+	switch layer.Type {
+	case viewmodel.LayerTypeCondition, viewmodel.LayerTypeHTTP:
+		return layer.Options
+	case viewmodel.LayerTypeGoto:
+		if target, err := s.gotoTarget(layer, root); err == nil {
+			return []viewmodel.Layer{target}
+		}
+	case viewmodel.LayerTypeDynamic:
+		if layer.Source != nil {
+			if target, err := s.gotoTarget(viewmodel.Layer{Goto: layer.Source.Empty}, root); err == nil {
+				return []viewmodel.Layer{target}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *layerService) PreviousLayer(current string, root viewmodel.Layer, session *viewmodel.BotSession, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	parent, ok := flow.Parent(root, current)
+	for ok && s.isPassthrough(parent) {
+		if parent.ID == root.ID {
+			return s.EnterLayer(root, session, root, flowContext)
+		}
+		parent, ok = flow.Parent(root, parent.ID)
+	}
+
+	if !ok {
+		return s.EnterLayer(root, session, root, flowContext)
+	}
+	if parent.Type == viewmodel.LayerTypeDynamic {
+		return s.EnterLayer(parent, session, root, flowContext)
+	}
+	session.Page = 0
+
+	return parent, nil
+}
+
+func (s *layerService) isPassthrough(layer viewmodel.Layer) bool {
+	switch layer.Type {
+	case viewmodel.LayerTypeCondition, viewmodel.LayerTypeGoto, viewmodel.LayerTypeHTTP:
+		return true
 	}
 
-	option, err := strconv.Atoi(state)
+	return false
+}
+
+func (s *layerService) templateValues(session viewmodel.BotSession, flowContext viewmodel.FlowContext) map[string]string {
+	values := make(map[string]string)
+	for name, value := range flowContext.Attributes {
+		values[name] = value
+	}
+	for name, value := range session.Variables {
+		values["vars."+name] = value
+	}
+
+	return values
+}
+
+func (s *layerService) renderRequest(request viewmodel.HTTPRequest, values map[string]string, now time.Time) viewmodel.HTTPRequest {
+	headers := request.Headers
+	request.URL = render.Render(request.URL, values, now)
+	request.Body = render.JSON(request.Body, values, now)
+	request.Headers = make(map[string]string)
+	for key, value := range headers {
+		request.Headers[key] = render.Render(value, values, now)
+	}
+
+	return request
+}
+
+func (s *layerService) callAction(layer viewmodel.Layer, session *viewmodel.BotSession, flowContext viewmodel.FlowContext) {
+	// Layers fetched from LAYER_URL are not validated, a missing request takes
+	// the failure branch instead of failing the whole webhook.
+	if layer.Request == nil {
+		flowContext.Attributes["http.status"] = "0"
+		flowContext.Attributes["http.ok"] = "false"
+		flowContext.Attributes["http.error"] = "http node has no request"
+		return
+	}
+
+	request := s.renderRequest(*layer.Request, s.templateValues(*session, flowContext), flowContext.Now)
+
+	if session.Variables == nil {
+		session.Variables = make(map[string]string)
+	}
+	for variable := range request.Response {
+		delete(session.Variables, variable)
+	}
+
+	response, err := s.actionRepository.Call(request)
 	if err != nil {
-		return layer, errors.New("Mohon untuk menjawab pilihan layanan hanya dalam format angka (misal: ketik '1'), sesuai dengan pilihan yang disediakan. Terima kasih")
+		flowContext.Attributes["http.status"] = "0"
+		flowContext.Attributes["http.ok"] = "false"
+		flowContext.Attributes["http.error"] = err.Error()
+		return
+	}
+
+	ok := response.Status >= 200 && response.Status < 300
+	flowContext.Attributes["http.status"] = strconv.Itoa(response.Status)
+	flowContext.Attributes["http.ok"] = strconv.FormatBool(ok)
+	delete(flowContext.Attributes, "http.error")
+
+	var data interface{}
+	if err := json.Unmarshal(response.Body, &data); err != nil {
+		return
 	}
 
-	if option <= 0 || option > len(layer.Options) {
-		return layer, errors.New("Mohon untuk menjawab pilihan layanan hanya dalam format angka (misal: ketik '1'), sesuai dengan pilihan yang disediakan. Terima kasih")
+	for variable, path := range request.Response {
+		if value, exist := jsonpath.Lookup(data, path); exist {
+			session.Variables[variable] = value
+		}
 	}
+}
 
-	layer = layer.Options[option-1]
+func (s *layerService) MatchCommand(state string, layer viewmodel.Layer) (viewmodel.Command, bool) {
+	for _, command := range s.commands(layer) {
+		if input.MatchKeyword(state, command.Keywords...) {
+			return command, true
+		}
+	}
 
-	return layer, nil
+	return viewmodel.Command{}, false
+}
+
+func (s *layerService) commands(layer viewmodel.Layer) []viewmodel.Command {
+	commands := layer.Commands
+
+	if prevLayerKeypad, prevLayerKeypadEnable := os.LookupEnv("RETURN_PREVIOUS_LAYER_KEYPAD"); prevLayerKeypadEnable {
+		commands = append(commands[:len(commands):len(commands)], viewmodel.Command{
+			Keywords: []string{prevLayerKeypad},
+			Action:   viewmodel.CommandBack,
+		})
+	}
+
+	if directKeypad, directAssignEnable := os.LookupEnv("DIRECT_ASSIGN_AGENT_KEYPAD"); directAssignEnable {
+		commands = append(commands[:len(commands):len(commands)], viewmodel.Command{
+			Keywords: []string{directKeypad},
+			Action:   viewmodel.CommandHandover,
+			Message:  wording.Get(wording.WaitingForAgent),
+		})
+	}
+
+	return commands
+}
+
+func (s *layerService) matchCondition(layer viewmodel.Layer, session *viewmodel.BotSession, flowContext viewmodel.FlowContext) (viewmodel.Layer, bool) {
+	lookup := func(field string) (string, bool) {
+		if strings.HasPrefix(field, "vars.") {
+			value, exist := session.Variables[strings.TrimPrefix(field, "vars.")]
+			return value, exist
+		}
+		value, exist := flowContext.Attributes[field]
+		return value, exist
+	}
+
+	for _, option := range layer.Options {
+		if condition.Match(option.When, lookup) {
+			return option, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
+}
+
+func (s *layerService) selectOption(state string, layer viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) (viewmodel.Layer, bool) {
+	if option, ok := input.Number(state); ok {
+		if menu.Enabled(layer, root.MenuFormat) && menu.Paginated(layer, root.MenuFormat) {
+			if option <= 0 || option > s.pageOptionCount(layer, root, session) {
+				return viewmodel.Layer{}, false
+			}
+			option += session.Page * menu.PageSize(root.MenuFormat)
+		}
+
+		if option <= 0 || option > len(layer.Options) {
+			return viewmodel.Layer{}, false
+		}
+		return layer.Options[option-1], true
+	}
+
+	for _, option := range layer.Options {
+		keywords := append(option.Title.Values(), option.Keywords...)
+		if input.MatchKeyword(state, keywords...) {
+			return option, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
 }
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories"
+	"bot-routing-engine/utils/flow"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func httpLayer(request *viewmodel.HTTPRequest) viewmodel.Layer {
+	layer := viewmodel.Layer{
+		Options: []viewmodel.Layer{{
+			ID:      "lookup",
+			Type:    viewmodel.LayerTypeHTTP,
+			Request: request,
+			Options: []viewmodel.Layer{
+				{ID: "found", When: []viewmodel.Rule{{Field: "http.ok", Value: "true"}}, Message: viewmodel.NewText("found")},
+				{ID: "failed", Message: viewmodel.NewText("failed")},
+			},
+		}},
+	}
+	flow.AssignIDs(&layer)
+
+	return layer
+}
+
+func enterHTTP(t *testing.T, layer viewmodel.Layer, session *viewmodel.BotSession) (viewmodel.Layer, viewmodel.FlowContext) {
+	service := NewLayerService(nil, nil, repositories.NewActionRepository(nil))
+	flowContext := viewmodel.FlowContext{Attributes: make(map[string]string), Now: time.Now()}
+
+	next, err := service.EnterLayer(layer.Options[0], session, layer, flowContext)
+	if err != nil {
+		t.Fatalf("EnterLayer: %v", err)
+	}
+
+	return next, flowContext
+}
+
+func TestHTTPNodeMapsResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("order") != "A-1" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"order":{"status":"shipped","items":[{"name":"shoe"}]}}`))
+	}))
+	defer server.Close()
+
+	layer := httpLayer(&viewmodel.HTTPRequest{
+		Method:   http.MethodPost,
+		URL:      server.URL + "/orders?order={{vars.order}}",
+		Headers:  map[string]string{"X-Token": "secret"},
+		Response: map[string]string{"status": "order.status", "item": "order.items.0.name", "missing": "order.missing"},
+	})
+	session := &viewmodel.BotSession{Variables: map[string]string{"order": "A-1", "missing": "stale"}}
+
+	next, flowContext := enterHTTP(t, layer, session)
+
+	if next.ID != "found" {
+		t.Errorf("expected the success branch, got %q", next.ID)
+	}
+	if flowContext.Attributes["http.status"] != "200" || flowContext.Attributes["http.ok"] != "true" {
+		t.Errorf("unexpected http attributes %v", flowContext.Attributes)
+	}
+	if session.Variables["status"] != "shipped" || session.Variables["item"] != "shoe" {
+		t.Errorf("response was not mapped, got %v", session.Variables)
+	}
+	if _, exist := session.Variables["missing"]; exist {
+		t.Errorf("unmatched response path should clear the previous value")
+	}
+}
+
+func TestHTTPNodeEscapesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body) != 2 || body["order"] != `A-1", "admin": "true` || body["name"] != "BUDI" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	layer := httpLayer(&viewmodel.HTTPRequest{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Body:   `{"order": "{{vars.order}}", "name": "{{vars.name | upper | json}}"}`,
+	})
+	session := &viewmodel.BotSession{Variables: map[string]string{"order": `A-1", "admin": "true`, "name": "budi"}}
+
+	next, flowContext := enterHTTP(t, layer, session)
+
+	if next.ID != "found" {
+		t.Errorf("expected the success branch, got %q with status %s", next.ID, flowContext.Attributes["http.status"])
+	}
+}
+
+func TestHTTPNodeFailureBranch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		request *viewmodel.HTTPRequest
+		status  string
+	}{
+		{"server error", &viewmodel.HTTPRequest{URL: server.URL}, "500"},
+		{"unreachable", &viewmodel.HTTPRequest{URL: "http://127.0.0.1:0"}, "0"},
+		{"missing request", nil, "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, flowContext := enterHTTP(t, httpLayer(test.request), &viewmodel.BotSession{})
+
+			if next.ID != "failed" {
+				t.Errorf("expected the fallback branch, got %q", next.ID)
+			}
+			if flowContext.Attributes["http.status"] != test.status || flowContext.Attributes["http.ok"] != "false" {
+				t.Errorf("unexpected http attributes %v", flowContext.Attributes)
+			}
+		})
+	}
+}
+
+func TestDynamicPostbackEntersItem(t *testing.T) {
+	layer := viewmodel.Layer{
+		Options: []viewmodel.Layer{{
+			ID:     "pick",
+			Type:   viewmodel.LayerTypeDynamic,
+			Source: &viewmodel.DynamicSource{Variable: "list", Save: "order", Empty: flow.RootID},
+			Options: []viewmodel.Layer{
+				{ID: "done", Message: viewmodel.NewText("You picked {{vars.order}}")},
+			},
+		}},
+	}
+	flow.AssignIDs(&layer)
+
+	service := NewLayerService(nil, nil, nil)
+	flowContext := viewmodel.FlowContext{Attributes: make(map[string]string), Now: time.Now()}
+	session := &viewmodel.BotSession{Variables: map[string]string{"list": "A-1, B-2"}}
+
+	current, err := service.EnterLayer(layer.Options[0], session, layer, flowContext)
+	if err != nil {
+		t.Fatalf("EnterLayer: %v", err)
+	}
+	session.Layer = current.ID
+
+	if _, err = service.DeterminePostback("done", session, layer, flowContext); err != ErrOptionNotFound {
+		t.Errorf("the follow-up option must not be selectable, got %v", err)
+	}
+
+	next, err := service.DeterminePostback("pick#2", session, layer, flowContext)
+	if err != nil {
+		t.Fatalf("DeterminePostback: %v", err)
+	}
+	if next.ID != "done" || session.Variables["order"] != "B-2" {
+		t.Errorf("expected item B-2 to be entered, got %q with %v", next.ID, session.Variables)
+	}
+}
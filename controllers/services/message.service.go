@@ -3,25 +3,42 @@ package services
 import (
 	"bot-routing-engine/entities/viewmodel"
 	"bot-routing-engine/repositories"
+	"bot-routing-engine/utils/flow"
+	"bot-routing-engine/utils/render"
+	"bot-routing-engine/utils/rich"
+	"bot-routing-engine/utils/wording"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"time"
 )
 
+// ErrMessageSeen is returned for a retried webhook whose message already
+// moved the session, it is acknowledged without being processed again.
+var ErrMessageSeen = errors.New("message was already processed")
+
 type MessageService interface {
 	Determine(request interface{}) (drafts []viewmodel.Draft, err error)
 }
 
 type messageService struct {
 	multichannelRepository repositories.MultichannelRepository
+	layer                  LayerService
 	room                   roomService
+	scheduler              SchedulerService
+	clock                  func() time.Time
+}
+
+func NewMessageService(multichannelRepository repositories.MultichannelRepository, layer LayerService, room roomService, scheduler SchedulerService) *messageService {
+	return &messageService{multichannelRepository, layer, room, scheduler, time.Now}
 }
 
-func NewMessageService(multichannelRepository repositories.MultichannelRepository, room roomService) *messageService {
-	return &messageService{multichannelRepository, room}
+func (s *messageService) SetClock(clock func() time.Time) {
+	s.clock = clock
 }
 
 func (s *messageService) Determine(request interface{}) (drafts []viewmodel.Draft, err error) {
@@ -30,88 +47,333 @@ func (s *messageService) Determine(request interface{}) (drafts []viewmodel.Draf
 	var roomOption viewmodel.Option
 	json.Unmarshal([]byte(input.Payload.Room.Options), &roomOption)
 
-	roomInfo, err := s.room.SDKGetRoomInfo(input.Payload.Room.ID)
+	layer, err := s.layer.GetLayer(roomOption.ChannelDetails.ChannelID)
 	if err != nil {
 		return
 	}
 
-	layer, err := NewLayerService().GetLayer(roomOption.ChannelDetails.ChannelID)
+	officeHour, _ := s.multichannelRepository.OfficeHour()
+
+	var session viewmodel.BotSession
+	flowContext := s.flowContext(input, roomOption, officeHour)
+	drafts, err = s.determine(input, roomOption, layer, officeHour, flowContext, &session)
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	for name, value := range session.Variables {
+		flowContext.Attributes["vars."+name] = value
 	}
+	for i := range drafts {
+		drafts[i].Message = render.Render(drafts[i].Message, flowContext.Attributes, flowContext.Now)
+		if drafts[i].Outbound != nil {
+			drafts[i].Outbound.Payload = render.Payload(drafts[i].Outbound.Payload, flowContext.Attributes, flowContext.Now)
+		}
+		for j, tag := range drafts[i].Tags {
+			drafts[i].Tags[j] = strings.TrimSpace(render.Render(tag, flowContext.Attributes, flowContext.Now))
+		}
+		for key, value := range drafts[i].AdditionalInfo {
+			drafts[i].AdditionalInfo[key] = render.Render(value, flowContext.Attributes, flowContext.Now)
+		}
+	}
+
+	// The session is already saved, a timer failure only costs the reminder
+	// and must not hold back the reply.
+	if err = s.trackActivity(input.Payload.Room.ID, layer, session, drafts, flowContext); err != nil {
+		s.room.logger.Printf("SCHEDULER: room %s: %s", input.Payload.Room.ID, err.Error())
+	}
+
+	return drafts, nil
+}
 
+func (s *messageService) trackActivity(roomID string, layer viewmodel.Layer, session viewmodel.BotSession, drafts []viewmodel.Draft, flowContext viewmodel.FlowContext) error {
+	for _, draft := range drafts {
+		if draft.Layer.Handover || draft.Layer.Resolve {
+			return s.scheduler.Clear(roomID)
+		}
+	}
+
+	current, exist := flow.Find(layer, session.Layer)
+	if !exist || current.Timeout == nil {
+		return s.scheduler.Clear(roomID)
+	}
+
+	reminderMessage := render.Render(s.localize(current.Timeout.ReminderMessage, session), flowContext.Attributes, flowContext.Now)
+	resolveMessage := render.Render(s.localize(current.Timeout.ResolveMessage, session), flowContext.Attributes, flowContext.Now)
+
+	return s.scheduler.Track(roomID, current.Timeout, reminderMessage, resolveMessage)
+}
+
+func (s *messageService) determine(input *viewmodel.WebhookRequest, roomOption viewmodel.Option, layer viewmodel.Layer, officeHour viewmodel.OfficeHourResp, flowContext viewmodel.FlowContext, session *viewmodel.BotSession) (drafts []viewmodel.Draft, err error) {
 	draft := viewmodel.Draft{
 		Room:  input,
 		Layer: layer,
 	}
 
-	if !s.isOnWorkingHour() {
+	stored, stateExist, err := s.room.BotSession(input.Payload.Room.ID)
+	if err != nil {
+		return nil, err
+	}
+	*session = stored
+
+	if messageID := webhookMessageID(input); len(messageID) > 0 {
+		seen, err := s.room.Seen(input.Payload.Room.ID, messageID)
+		if err != nil {
+			return nil, err
+		}
+		if seen {
+			return nil, ErrMessageSeen
+		}
+	}
+
+	if len(session.Locale) == 0 {
+		session.Locale = wording.DefaultLocale(roomOption.ChannelDetails.ChannelID)
+	}
+
+	if !s.isOnWorkingHour(officeHour) {
 		notInWorkingHourDraft := viewmodel.Draft{
 			Room: input,
 			Layer: viewmodel.Layer{
 				Resolve: true,
 			},
-			Message: os.Getenv("NOT_IN_WORKING_HOUR_WORDING"),
+			Message: s.localize(wording.Get(wording.NotInWorkingHour), *session),
 		}
 		drafts = append(drafts, notInWorkingHourDraft)
 
 		return drafts, nil
 	}
 
-	var states []int
 	option := input.Payload.Message.Text
-	if !s.room.StateExist(roomInfo) {
-		s.room.UpdateBotState(input.Payload.Room.ID, states, roomInfo)
-		draft.Message = layer.Message
-		drafts = append(drafts, draft)
-	} else {
-		var jsonOptions map[string]json.RawMessage
-		json.Unmarshal([]byte(roomInfo.Results.Rooms[0].Options), &jsonOptions)
-		json.Unmarshal(jsonOptions["bot_layer"], &states)
-
-		if directKeypad, directAssignEnable := os.LookupEnv("DIRECT_ASSIGN_AGENT_KEYPAD"); directAssignEnable && input.Payload.Message.Text == directKeypad && len(states) == 0 {
-			directHandoverDraft := viewmodel.Draft{
-				Room: input,
-				Layer: viewmodel.Layer{
-					Handover: true,
-				},
-				Message: os.Getenv("WAITING_FOR_AGENT_WORDING"),
-			}
-			drafts = append(drafts, directHandoverDraft)
-			return
+	if !stateExist {
+		entryLayer, err := s.layer.EnterLayer(layer, session, layer, flowContext)
+		if err != nil {
+			return nil, err
+		}
+		session.Layer = entryLayer.ID
+		if err = s.saveSession(input, *session); err != nil {
+			return nil, err
 		}
+		drafts = append(drafts, s.nodeDraft(input, entryLayer, layer, *session, flowContext))
+		return drafts, nil
+	}
 
-		choosenLayer, err := NewLayerService().DetermineLayer(option, states, layer)
-		if err != nil {
-			draft.Message = err.Error()
-			drafts = append(drafts, draft)
-			draft.Message = choosenLayer.Message
-			drafts = append(drafts, draft)
-			return drafts, nil
-		}
-
-		if prevLayerKeypad, prevLayerKeypadEnable := os.LookupEnv("RETURN_PREVIOUS_LAYER_KEYPAD"); prevLayerKeypadEnable &&
-			input.Payload.Message.Text == prevLayerKeypad && len(states) > 0 {
-			states = states[:len(states)-1]
-		} else {
-			optionInt, _ := strconv.Atoi(option)
-			states = append(states, optionInt)
-		}
-		s.room.UpdateBotState(input.Payload.Room.ID, states, roomInfo)
-		draft.Message = choosenLayer.Message
-		draft.Layer = choosenLayer
+	state, migrated := s.layer.ResolveState(session.Layer, layer)
+	session.Layer = state
+	if migrated {
+		if err = s.saveSession(input, *session); err != nil {
+			return nil, err
+		}
+	}
+
+	choosenLayer, err := s.determinePostback(input, session, layer, flowContext)
+	if errors.Is(err, ErrOptionNotFound) {
+		if command, matched := s.layer.MatchCommand(option, layer); matched {
+			return s.runCommand(command, input, layer, flowContext, session)
+		}
+
+		choosenLayer, err = s.layer.DetermineLayer(option, session, layer, flowContext)
+	}
+	var replyErr *ReplyError
+	if errors.As(err, &replyErr) {
+		session.Attempts++
+		if limit := s.invalidLimit(choosenLayer, layer); limit != nil && session.Attempts >= limit.Attempts {
+			session.Attempts = 0
+			return s.runCommand(limitCommand(*limit), input, layer, flowContext, session)
+		}
+		if err = s.saveSession(input, *session); err != nil {
+			return nil, err
+		}
+
+		draft.Message = s.localize(replyErr.Text, *session)
 		drafts = append(drafts, draft)
+		drafts = append(drafts, s.nodeDraft(input, choosenLayer, layer, *session, flowContext))
 		return drafts, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	return
+	session.Layer = choosenLayer.ID
+	session.Attempts = 0
+	if err = s.saveSession(input, *session); err != nil {
+		return nil, err
+	}
+	drafts = append(drafts, s.nodeDraft(input, choosenLayer, layer, *session, flowContext))
+	return drafts, nil
 }
 
-func (s *messageService) isOnWorkingHour() bool {
-	officeHour, _ := s.multichannelRepository.OfficeHour()
+func (s *messageService) runCommand(command viewmodel.Command, input *viewmodel.WebhookRequest, layer viewmodel.Layer, flowContext viewmodel.FlowContext, session *viewmodel.BotSession) (drafts []viewmodel.Draft, err error) {
+	message := s.localize(command.Message, *session)
+
+	switch command.Action {
+	case viewmodel.CommandHandover, viewmodel.CommandResolve:
+		drafts = append(drafts, viewmodel.Draft{
+			Room: input,
+			Layer: viewmodel.Layer{
+				Handover: command.Action == viewmodel.CommandHandover,
+				Resolve:  command.Action == viewmodel.CommandResolve,
+				Division: command.Division,
+			},
+			Message: message,
+		})
+		return drafts, nil
+	}
+
+	current, exist := flow.Find(layer, session.Layer)
+	if !exist {
+		current = layer
+	}
+
+	switch command.Action {
+	case viewmodel.CommandRoot:
+		current, err = s.layer.EnterLayer(layer, session, layer, flowContext)
+	case viewmodel.CommandBack:
+		current, err = s.layer.PreviousLayer(session.Layer, layer, session, flowContext)
+	case viewmodel.CommandGoto:
+		current, err = s.layer.EnterLayer(viewmodel.Layer{Type: viewmodel.LayerTypeGoto, Goto: command.Goto}, session, layer, flowContext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message) > 0 {
+		drafts = append(drafts, viewmodel.Draft{Room: input, Layer: layer, Message: message})
+	}
+
+	session.Layer = current.ID
+	session.Attempts = 0
+	if err = s.saveSession(input, *session); err != nil {
+		return nil, err
+	}
+	drafts = append(drafts, s.nodeDraft(input, current, layer, *session, flowContext))
+
+	return drafts, nil
+}
+
+func (s *messageService) saveSession(input *viewmodel.WebhookRequest, session viewmodel.BotSession) error {
+	return s.room.UpdateBotState(input.Payload.Room.ID, session, webhookMessageID(input))
+}
+
+func webhookMessageID(input *viewmodel.WebhookRequest) string {
+	message := input.Payload.Message
+	if message.ID != 0 {
+		return strconv.Itoa(message.ID)
+	}
+	if len(message.IDStr) > 0 {
+		return message.IDStr
+	}
+
+	return message.UniqueTempID
+}
+
+func (s *messageService) invalidLimit(current viewmodel.Layer, root viewmodel.Layer) *viewmodel.InvalidLimit {
+	if current.InvalidLimit != nil {
+		return current.InvalidLimit
+	}
+
+	return root.InvalidLimit
+}
+
+func (s *messageService) determinePostback(input *viewmodel.WebhookRequest, session *viewmodel.BotSession, layer viewmodel.Layer, flowContext viewmodel.FlowContext) (viewmodel.Layer, error) {
+	optionID, ok := rich.OptionID(input.Payload.Message.Payload)
+	if !ok {
+		return viewmodel.Layer{}, ErrOptionNotFound
+	}
+
+	return s.layer.DeterminePostback(optionID, session, layer, flowContext)
+}
+
+func (s *messageService) nodeDraft(input *viewmodel.WebhookRequest, node viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession, flowContext viewmodel.FlowContext) viewmodel.Draft {
+	draft := viewmodel.Draft{
+		Room:           input,
+		Layer:          node,
+		Message:        s.nodeMessage(node, root, session),
+		Tags:           append([]string(nil), node.Tags...),
+		AdditionalInfo: make(map[string]string),
+	}
+	for key, value := range node.AdditionalInfo {
+		draft.AdditionalInfo[key] = value
+	}
+
+	if node.Rich == nil {
+		return draft
+	}
+
+	// Dynamic nodes offer their listed items, not the follow-up option.
+	options := s.layer.DynamicLayer(node, session)
+	message := s.localize(node.Message, session)
+	if rich.Supported(flowContext.Attributes["channel.source"], node.Rich.Type) {
+		outbound := rich.Build(options, message, session.Locale, wording.FallbackLocale())
+		draft.Outbound = &outbound
+		if len(message) == 0 {
+			draft.Message = node.Rich.Text.Get(session.Locale, wording.FallbackLocale())
+		}
+	} else {
+		// Fallback leaves the options out when the auto menu lists them, the
+		// menu is added back the same way nodeMessage does.
+		draft.Message = rich.Fallback(options, message, root.MenuFormat, session.Locale, wording.FallbackLocale())
+		if menu := s.layer.Menu(node, root, session); len(menu) > 0 {
+			draft.Message = strings.TrimSpace(draft.Message + "\n\n" + menu)
+		}
+	}
+
+	return draft
+}
+
+func (s *messageService) nodeMessage(node viewmodel.Layer, root viewmodel.Layer, session viewmodel.BotSession) string {
+	message := s.localize(node.Message, session)
+
+	if menu := s.layer.Menu(node, root, session); len(menu) > 0 {
+		if len(message) > 0 {
+			return message + "\n\n" + menu
+		}
+		return menu
+	}
+
+	return message
+}
+
+func (s *messageService) localize(text viewmodel.Text, session viewmodel.BotSession) string {
+	return text.Get(session.Locale, wording.FallbackLocale())
+}
+
+func (s *messageService) flowContext(input *viewmodel.WebhookRequest, roomOption viewmodel.Option, officeHour viewmodel.OfficeHourResp) viewmodel.FlowContext {
+	loc, _ := time.LoadLocation(os.Getenv("TIMEZONE"))
+	now := s.clock().In(loc)
+
+	values := map[string]string{
+		"customer.name":               input.Payload.From.Name,
+		"customer.email":              input.Payload.From.Email,
+		"customer.avatar":             input.Payload.From.AvatarURL,
+		"room.id":                     input.Payload.Room.ID,
+		"room.name":                   input.Payload.Room.Name,
+		"channel.name":                roomOption.Channel,
+		"channel.source":              roomOption.Source,
+		"channel.id":                  strconv.Itoa(roomOption.ChannelDetails.ChannelID),
+		"office_hour.timezone":        officeHour.Data.Timezone,
+		"office_hour.online_message":  officeHour.Data.OnlineMessage,
+		"office_hour.offline_message": officeHour.Data.OfflineMessage,
+		"time.weekday":                strconv.Itoa(int(now.Weekday())),
+		"time.day":                    strings.ToLower(now.Weekday().String()),
+		"time.hour":                   strconv.Itoa(now.Hour()),
+		"time.minute":                 strconv.Itoa(now.Minute()),
+	}
+
+	for _, day := range officeHour.Data.OfficeHours {
+		if int(now.Weekday()) == day.Day || int(time.Saturday)+1 == day.Day {
+			values["office_hour.start"] = day.Starttime
+			values["office_hour.end"] = day.Endtime
+			break
+		}
+	}
+
+	return viewmodel.FlowContext{Attributes: values, Now: now}
+}
+
+func (s *messageService) isOnWorkingHour(officeHour viewmodel.OfficeHourResp) bool {
 	loc, _ := time.LoadLocation(os.Getenv("TIMEZONE"))
 
-	now := time.Now().In(loc)
+	now := s.clock().In(loc)
 	for _, day := range officeHour.Data.OfficeHours {
 		if int(now.Weekday()) == day.Day || int(time.Saturday)+1 == day.Day {
 			officeHourStartTime := fmt.Sprintf("%d-%d-%d %s", now.Year(), now.Month(), now.Day(), day.Starttime)
@@ -5,19 +5,29 @@ import (
 	"bot-routing-engine/entities/viewmodel"
 	"bot-routing-engine/repositories"
 	"bot-routing-engine/utils/agent"
-	"encoding/json"
+	"log"
 	"os"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type RoomService interface {
 	SendBotMessage(roomID string, message string) error
+	SendDraft(draft viewmodel.Draft) error
+	Dispatch(drafts []viewmodel.Draft) error
 	Resolve(roomID string, lastCommentID string) error
 	SDKGetRoomInfo(ID string) (entities.Room, error)
-	UpdateBotState(roomID string, states []int, roomInfo entities.Room) error
-	StateExist(room entities.Room) bool
+	BotSession(roomID string) (viewmodel.BotSession, bool, error)
+	UpdateBotState(roomID string, session viewmodel.BotSession, messageID string) error
+	StateExist(roomID string) (bool, error)
+	ResetBotLayers(roomID string) error
+	Lock(roomID string) (func(), error)
+	Seen(roomID string, messageID string) (bool, error)
 	QismoRoomInfo(ID string) (viewmodel.QismoRoomInfo, error)
 	AutoResolveTag(ID string) error
+	TagRoom(ID string, tags []string) error
+	UpdateAdditionalInfo(ID string, info map[string]string) error
 	Handover(ID string) error
 	HandoverWithDivision(ID string, division string) error
 	Deactivate(ID string) error
@@ -26,10 +36,13 @@ type RoomService interface {
 type roomService struct {
 	multichannelRepository repositories.MultichannelRepository
 	roomRepository         repositories.RoomRepository
+	sessionStore           repositories.SessionStore
+	lockRepository         repositories.LockRepository
+	logger                 *log.Logger
 }
 
-func NewRoomService(multichannelRepository repositories.MultichannelRepository, roomRepository repositories.RoomRepository) *roomService {
-	return &roomService{multichannelRepository, roomRepository}
+func NewRoomService(multichannelRepository repositories.MultichannelRepository, roomRepository repositories.RoomRepository, sessionStore repositories.SessionStore, lockRepository repositories.LockRepository, logger *log.Logger) *roomService {
+	return &roomService{multichannelRepository, roomRepository, sessionStore, lockRepository, logger}
 }
 
 func (s *roomService) SendBotMessage(roomID string, message string) error {
@@ -42,8 +55,66 @@ func (s *roomService) SendBotMessage(roomID string, message string) error {
 	return nil
 }
 
+func (s *roomService) SendDraft(draft viewmodel.Draft) error {
+	if draft.Outbound == nil {
+		return s.SendBotMessage(draft.Room.Payload.Room.ID, draft.Message)
+	}
+
+	return s.multichannelRepository.SendBotPayload(draft.Room.Payload.Room.ID, draft.Message, draft.Outbound.Type, draft.Outbound.Payload)
+}
+
+func (s *roomService) Dispatch(drafts []viewmodel.Draft) error {
+	for _, draft := range drafts {
+		roomID := draft.Room.Payload.Room.ID
+
+		var err error
+		if (!draft.Layer.Handover && !draft.Layer.Resolve) || len(draft.Message) > 0 {
+			err = s.SendDraft(draft)
+			if err != nil {
+				return err
+			}
+		}
+
+		// The session is already saved by now, tags and additional info are
+		// best effort so a failure never holds back the reply or the handover.
+		if err = s.TagRoom(roomID, draft.Tags); err != nil {
+			s.logger.Printf("DISPATCH: room %s: tag: %s", roomID, err.Error())
+		}
+
+		if err = s.UpdateAdditionalInfo(roomID, draft.AdditionalInfo); err != nil {
+			s.logger.Printf("DISPATCH: room %s: additional info: %s", roomID, err.Error())
+		}
+
+		if draft.Layer.Resolve && !draft.Layer.Handover {
+			qismoRoomInfo, err := s.QismoRoomInfo(roomID)
+			if err != nil {
+				return err
+			}
+
+			err = s.Resolve(roomID, strconv.Itoa(qismoRoomInfo.Data.CustomerRoom.ID))
+			if err != nil {
+				return err
+			}
+		}
+
+		if draft.Layer.Handover {
+			if len(draft.Layer.Division) > 0 {
+				err = s.HandoverWithDivision(roomID, draft.Layer.Division)
+			} else {
+				err = s.Handover(roomID)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *roomService) Resolve(roomID string, lastCommentID string) error {
-	err := s.roomRepository.ResetBotLayers(roomID)
+	err := s.ResetBotLayers(roomID)
 	if err != nil {
 		return err
 	}
@@ -67,27 +138,36 @@ func (s *roomService) SDKGetRoomInfo(ID string) (entities.Room, error) {
 	return room, nil
 }
 
-func (s *roomService) UpdateBotState(roomID string, states []int, roomInfo entities.Room) error {
-	var roomOptions map[string]interface{}
-	json.Unmarshal([]byte(roomInfo.Results.Rooms[0].Options), &roomOptions)
-
-	roomOptions["bot_layer"] = states
+func (s *roomService) BotSession(roomID string) (viewmodel.BotSession, bool, error) {
+	return s.sessionStore.Load(roomID)
+}
 
-	roomOptionsJson, err := json.Marshal(roomOptions)
-	if err != nil {
-		return err
+// UpdateBotState saves the session and marks the message that moved it as
+// seen, WEBHOOK_DEDUP_TTL in minutes sets how long retries of it are skipped.
+func (s *roomService) UpdateBotState(roomID string, session viewmodel.BotSession, messageID string) error {
+	ttl, err := strconv.Atoi(os.Getenv("WEBHOOK_DEDUP_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 60
 	}
 
-	err = s.roomRepository.UpdateRoom(roomID, string(roomOptionsJson))
-	if err != nil {
-		return err
-	}
+	return s.sessionStore.Save(roomID, session, messageID, time.Duration(ttl)*time.Minute)
+}
 
-	return nil
+func (s *roomService) StateExist(roomID string) (bool, error) {
+	_, exist, err := s.sessionStore.Load(roomID)
+	return exist, err
 }
 
-func (s *roomService) StateExist(room entities.Room) bool {
-	return s.roomRepository.StateExist(room)
+func (s *roomService) ResetBotLayers(roomID string) error {
+	return s.sessionStore.Delete(roomID)
+}
+
+func (s *roomService) Lock(roomID string) (func(), error) {
+	return s.lockRepository.Lock(roomID)
+}
+
+func (s *roomService) Seen(roomID string, messageID string) (bool, error) {
+	return s.sessionStore.Seen(roomID, messageID)
 }
 
 func (s *roomService) QismoRoomInfo(ID string) (viewmodel.QismoRoomInfo, error) {
@@ -103,13 +183,60 @@ func (s *roomService) AutoResolveTag(ID string) error {
 	return s.roomRepository.TagRoom(ID, os.Getenv("AUTO_RESOLVE_TAG"))
 }
 
+func (s *roomService) TagRoom(ID string, tags []string) error {
+	for _, tag := range tags {
+		if len(tag) == 0 {
+			continue
+		}
+
+		err := s.roomRepository.TagRoom(ID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *roomService) UpdateAdditionalInfo(ID string, info map[string]string) error {
+	if len(info) == 0 {
+		return nil
+	}
+
+	properties, err := s.roomRepository.GetUserInfo(ID)
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[string]bool)
+	for i, property := range properties {
+		if value, exist := info[property.Key]; exist {
+			properties[i].Value = value
+			updated[property.Key] = true
+		}
+	}
+
+	var keys []string
+	for key := range info {
+		if !updated[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		properties = append(properties, viewmodel.UserProperty{Key: key, Value: info[key]})
+	}
+
+	return s.roomRepository.UpdateUserInfo(ID, properties)
+}
+
 func (s *roomService) Handover(ID string) error {
 	agents, err := s.multichannelRepository.GetAllAgents(100)
 	if err != nil {
 		return err
 	}
 
-	err = s.roomRepository.ResetBotLayers(ID)
+	err = s.ResetBotLayers(ID)
 	if err != nil {
 		return err
 	}
@@ -156,7 +283,7 @@ func (s *roomService) assignPoolAgent(roomID string, agents []viewmodel.Agent) e
 }
 
 func (s *roomService) HandoverWithDivision(ID string, divisionName string) error {
-	err := s.roomRepository.ResetBotLayers(ID)
+	err := s.ResetBotLayers(ID)
 	if err != nil {
 		return err
 	}
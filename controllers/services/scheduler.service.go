@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories"
+	"log"
+	"strconv"
+	"time"
+)
+
+type SchedulerService interface {
+	Track(roomID string, timeout *viewmodel.Timeout, reminderMessage string, resolveMessage string) error
+	Clear(roomID string) error
+	Tick(now time.Time)
+	Start(interval time.Duration)
+	Stop()
+}
+
+type schedulerService struct {
+	timerRepository repositories.TimerRepository
+	room            RoomService
+	logger          *log.Logger
+	stop            chan struct{}
+	clock           func() time.Time
+}
+
+func NewSchedulerService(timerRepository repositories.TimerRepository, room RoomService, logger *log.Logger) *schedulerService {
+	return &schedulerService{
+		timerRepository: timerRepository,
+		room:            room,
+		logger:          logger,
+		stop:            make(chan struct{}),
+		clock:           time.Now,
+	}
+}
+
+func (s *schedulerService) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+func (s *schedulerService) Track(roomID string, timeout *viewmodel.Timeout, reminderMessage string, resolveMessage string) error {
+	if timeout == nil || (timeout.ReminderAfter <= 0 && timeout.ResolveAfter <= 0) {
+		return s.Clear(roomID)
+	}
+
+	now := s.clock()
+	timer := viewmodel.RoomTimer{
+		RoomID:       roomID,
+		LastActivity: now,
+	}
+
+	if timeout.ReminderAfter > 0 && len(reminderMessage) > 0 {
+		remindAt := now.Add(time.Duration(timeout.ReminderAfter) * time.Minute)
+		timer.RemindAt = &remindAt
+		timer.ReminderMessage = reminderMessage
+	}
+
+	if timeout.ResolveAfter > 0 {
+		resolveAt := now.Add(time.Duration(timeout.ResolveAfter) * time.Minute)
+		timer.ResolveAt = &resolveAt
+		timer.ResolveMessage = resolveMessage
+	}
+
+	return s.timerRepository.Save(timer)
+}
+
+func (s *schedulerService) Clear(roomID string) error {
+	return s.timerRepository.Delete(roomID)
+}
+
+func (s *schedulerService) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Tick(s.clock())
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *schedulerService) Stop() {
+	close(s.stop)
+}
+
+func (s *schedulerService) Tick(now time.Time) {
+	timers, err := s.timerRepository.All()
+	if err != nil {
+		s.logger.Printf("SCHEDULER: %s", err.Error())
+		return
+	}
+
+	for _, timer := range timers {
+		if err := s.fire(timer, now); err != nil {
+			s.logger.Printf("SCHEDULER: room %s: %s", timer.RoomID, err.Error())
+		}
+	}
+}
+
+func (s *schedulerService) fire(timer viewmodel.RoomTimer, now time.Time) error {
+	if !timerDue(timer, now) {
+		return nil
+	}
+
+	unlock, err := s.room.Lock(timer.RoomID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Re-read under the room lock, another instance sharing the timers may
+	// already have sent the reminder or resolved the room.
+	current, exist := s.timerRepository.Get(timer.RoomID)
+	if !exist || !current.LastActivity.Equal(timer.LastActivity) {
+		return nil
+	}
+	timer = current
+
+	if timer.ResolveAt != nil && !now.Before(*timer.ResolveAt) {
+		if err := s.timerRepository.Delete(timer.RoomID); err != nil {
+			return err
+		}
+
+		if len(timer.ResolveMessage) > 0 {
+			if err := s.room.SendBotMessage(timer.RoomID, timer.ResolveMessage); err != nil {
+				return err
+			}
+		}
+
+		qismoRoomInfo, err := s.room.QismoRoomInfo(timer.RoomID)
+		if err != nil {
+			return err
+		}
+
+		return s.room.Resolve(timer.RoomID, strconv.Itoa(qismoRoomInfo.Data.CustomerRoom.ID))
+	}
+
+	if timer.RemindAt != nil && !now.Before(*timer.RemindAt) {
+		if err := s.room.SendBotMessage(timer.RoomID, timer.ReminderMessage); err != nil {
+			return err
+		}
+
+		timer.RemindAt = nil
+		timer.ReminderMessage = ""
+		if timer.ResolveAt == nil {
+			return s.timerRepository.Delete(timer.RoomID)
+		}
+
+		return s.timerRepository.Save(timer)
+	}
+
+	return nil
+}
+
+func timerDue(timer viewmodel.RoomTimer, now time.Time) bool {
+	return (timer.ResolveAt != nil && !now.Before(*timer.ResolveAt)) || (timer.RemindAt != nil && !now.Before(*timer.RemindAt))
+}
@@ -1,20 +1,24 @@
 package controllers
 
 import (
+	"bot-routing-engine/controllers/services"
 	"bot-routing-engine/entities/viewmodel"
-	"fmt"
-	"io"
+	"bot-routing-engine/simulator"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
 
-type uploadController struct{}
+type uploadController struct {
+	layerService services.LayerService
+}
 
-func NewUploadController() *uploadController {
-	return &uploadController{}
+func NewUploadController(layerService services.LayerService) *uploadController {
+	return &uploadController{layerService}
 }
 
 func (controller *uploadController) Upload(ctx echo.Context) error {
@@ -31,28 +35,97 @@ func (controller *uploadController) Upload(ctx echo.Context) error {
 	}
 	defer src.Close()
 
-	filename := file.Filename
+	content, err := ioutil.ReadAll(src)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: err.Error()})
+		return err
+	}
+
+	layer, err := controller.layerService.ParseLayer(content)
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.LayerValidationResponse{
+			Message: "invalid layer",
+			Errors:  []viewmodel.LayerError{{Path: "$", Reason: err.Error()}},
+		})
+	}
+
+	layerErrors, err := controller.layerService.ValidateLayer(layer)
+	if err != nil {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.ErrorResponse{Message: err.Error()})
+	}
+
+	if len(layerErrors) > 0 {
+		return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.LayerValidationResponse{
+			Message: "invalid layer",
+			Errors:  layerErrors,
+		})
+	}
+
+	if os.Getenv("UPLOAD_REQUIRE_FLOW_TESTS") == "true" {
+		results, err := controller.runFlowTests(ctx, layer)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: err.Error()})
+		}
+
+		for _, result := range results {
+			if !result.Passed {
+				return ctx.JSON(http.StatusUnprocessableEntity, viewmodel.LayerTestResponse{
+					Message: "flow tests failed",
+					Results: results,
+				})
+			}
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename))
 	if os.Getenv("ALL_IN_ONE_JSON_ROUTE") == "true" {
-		filename = fmt.Sprintf("%s%s", "layer", filepath.Ext(filename))
+		name = "layer"
 	}
 
-	dir := fmt.Sprintf("layer/%s", filename)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		os.MkdirAll("./layer", 0700)
+	uploader := ctx.FormValue("uploader")
+	if len(uploader) == 0 {
+		uploader = ctx.RealIP()
 	}
 
-	dst, err := os.Create(dir)
+	version, err := controller.layerService.PublishLayer(name, content, uploader)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: err.Error()})
 		return err
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		ctx.JSON(http.StatusBadRequest, viewmodel.ErrorResponse{Message: err.Error()})
-		return err
+	return ctx.JSON(http.StatusOK, viewmodel.LayerUploadResponse{
+		Message: "success",
+		Errors:  []viewmodel.LayerError{},
+		Version: version,
+	})
+}
+
+func (controller *uploadController) runFlowTests(ctx echo.Context, layer viewmodel.Layer) ([]viewmodel.FlowTestResult, error) {
+	tests := layer.Tests
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range form.File["tests"] {
+		src, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(src)
+		src.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		suite, err := simulator.ParseSuite(file.Filename, content)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, suite.Tests...)
 	}
 
-	ctx.JSON(http.StatusOK, "success")
-	return nil
+	return simulator.RunTests(layer, tests), nil
 }
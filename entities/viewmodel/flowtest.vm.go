@@ -0,0 +1,54 @@
+package viewmodel
+
+type FlowTestSuite struct {
+	Layer     string             `json:"layer,omitempty" yaml:"layer,omitempty"`
+	Responses []FlowTestResponse `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Tests     []FlowTest         `json:"tests" yaml:"tests"`
+}
+
+type FlowTest struct {
+	Name        string             `json:"name" yaml:"name"`
+	Clock       string             `json:"clock,omitempty" yaml:"clock,omitempty"`
+	Source      string             `json:"source,omitempty" yaml:"source,omitempty"`
+	Channel     string             `json:"channel,omitempty" yaml:"channel,omitempty"`
+	ChannelID   int                `json:"channel_id,omitempty" yaml:"channel_id,omitempty"`
+	Customer    string             `json:"customer,omitempty" yaml:"customer,omitempty"`
+	Email       string             `json:"email,omitempty" yaml:"email,omitempty"`
+	OfficeHours string             `json:"office_hours,omitempty" yaml:"office_hours,omitempty"`
+	Responses   []FlowTestResponse `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Steps       []FlowTestStep     `json:"steps" yaml:"steps"`
+}
+
+// FlowTestResponse is the canned reply of an http or dynamic node request, a
+// URL ending with * matches by prefix and an empty method matches any.
+type FlowTestResponse struct {
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	URL    string `json:"url" yaml:"url"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Body   string `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type FlowTestStep struct {
+	Send     string           `json:"send,omitempty" yaml:"send,omitempty"`
+	Postback string           `json:"postback,omitempty" yaml:"postback,omitempty"`
+	Wait     string           `json:"wait,omitempty" yaml:"wait,omitempty"`
+	Expect   FlowTestExpected `json:"expect" yaml:"expect"`
+}
+
+type FlowTestExpected struct {
+	Messages []string          `json:"messages,omitempty" yaml:"messages,omitempty"`
+	Contains []string          `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Handover *bool             `json:"handover,omitempty" yaml:"handover,omitempty"`
+	Division string            `json:"division,omitempty" yaml:"division,omitempty"`
+	Resolve  *bool             `json:"resolve,omitempty" yaml:"resolve,omitempty"`
+	Tags     []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Info     map[string]string `json:"info,omitempty" yaml:"info,omitempty"`
+	State    string            `json:"state,omitempty" yaml:"state,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+}
+
+type FlowTestResult struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+}
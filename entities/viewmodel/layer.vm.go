@@ -1,10 +1,187 @@
 package viewmodel
 
+import "time"
+
+const (
+	LayerTypeCondition = "condition"
+	LayerTypeGoto      = "goto"
+	LayerTypeLanguage  = "language"
+	LayerTypeHTTP      = "http"
+	LayerTypeDynamic   = "dynamic"
+)
+
+const (
+	CommandRoot     = "root"
+	CommandBack     = "back"
+	CommandHandover = "handover"
+	CommandResolve  = "resolve"
+	CommandRepeat   = "repeat"
+	CommandGoto     = "goto"
+)
+
 type Layer struct {
-	Message  string  `json:"message"`
-	Options  []Layer `json:"options"`
-	Handover bool    `json:"handover"`
-	Input    bool    `json:"input"`
-	Resolve  bool    `json:"resolve"`
-	Division string  `json:"division"`
+	ID             string            `json:"id"`
+	Type           string            `json:"type,omitempty"`
+	When           []Rule            `json:"when,omitempty"`
+	Title          Text              `json:"title,omitempty"`
+	Keywords       []string          `json:"keywords,omitempty"`
+	Message        Text              `json:"message"`
+	Options        []Layer           `json:"options"`
+	Handover       bool              `json:"handover"`
+	Input          bool              `json:"input"`
+	Variable       string            `json:"variable,omitempty"`
+	Validation     *InputValidation  `json:"validation,omitempty"`
+	RetryMessage   Text              `json:"retry_message,omitempty"`
+	Resolve        bool              `json:"resolve"`
+	Division       string            `json:"division"`
+	Locale         string            `json:"locale,omitempty"`
+	Timeout        *Timeout          `json:"timeout,omitempty"`
+	Goto           string            `json:"goto,omitempty"`
+	Subflows       map[string]Layer  `json:"subflows,omitempty"`
+	Commands       []Command         `json:"commands,omitempty"`
+	Menu           bool              `json:"menu,omitempty"`
+	MenuFormat     *MenuFormat       `json:"menu_format,omitempty"`
+	Rich           *RichMessage      `json:"rich,omitempty"`
+	Request        *HTTPRequest      `json:"request,omitempty"`
+	Source         *DynamicSource    `json:"source,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	AdditionalInfo map[string]string `json:"additional_info,omitempty"`
+	InvalidLimit   *InvalidLimit     `json:"invalid_limit,omitempty"`
+	Tests          []FlowTest        `json:"tests,omitempty"`
+}
+
+type InvalidLimit struct {
+	Attempts int    `json:"attempts"`
+	Action   string `json:"action"`
+	Message  Text   `json:"message,omitempty"`
+	Division string `json:"division,omitempty"`
+	Goto     string `json:"goto,omitempty"`
+}
+
+type DynamicSource struct {
+	Request  *HTTPRequest `json:"request,omitempty"`
+	Variable string       `json:"variable,omitempty"`
+	Items    string       `json:"items,omitempty"`
+	Title    string       `json:"title,omitempty"`
+	Value    string       `json:"value,omitempty"`
+	Save     string       `json:"save"`
+	Empty    string       `json:"empty"`
+}
+
+type OptionSnapshot struct {
+	Node  string         `json:"node"`
+	Items []SnapshotItem `json:"items"`
+}
+
+type SnapshotItem struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type HTTPRequest struct {
+	Method   string            `json:"method,omitempty"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     string            `json:"body,omitempty"`
+	Timeout  int               `json:"timeout,omitempty"`
+	Response map[string]string `json:"response,omitempty"`
+}
+
+type HTTPResponse struct {
+	Status int
+	Body   []byte
+}
+
+type RichMessage struct {
+	Type    string     `json:"type"`
+	Text    Text       `json:"text,omitempty"`
+	URL     string     `json:"url,omitempty"`
+	Caption Text       `json:"caption,omitempty"`
+	Cards   []RichCard `json:"cards,omitempty"`
+}
+
+type RichCard struct {
+	Title       Text   `json:"title"`
+	Description Text   `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Option      string `json:"option,omitempty"`
+	ButtonLabel Text   `json:"button_label,omitempty"`
+}
+
+type MenuFormat struct {
+	Auto      bool   `json:"auto"`
+	Style     string `json:"style,omitempty"`
+	Separator string `json:"separator,omitempty"`
+	Header    Text   `json:"header,omitempty"`
+	Footer    Text   `json:"footer,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+	MoreLabel Text   `json:"more_label,omitempty"`
+}
+
+type Command struct {
+	Keywords []string `json:"keywords"`
+	Action   string   `json:"action"`
+	Message  Text     `json:"message,omitempty"`
+	Division string   `json:"division,omitempty"`
+	Goto     string   `json:"goto,omitempty"`
+}
+
+type InputValidation struct {
+	Type    string   `json:"type"`
+	Pattern string   `json:"pattern,omitempty"`
+	Format  string   `json:"format,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+}
+
+type Timeout struct {
+	ReminderAfter   int  `json:"reminder_after"`
+	ReminderMessage Text `json:"reminder_message,omitempty"`
+	ResolveAfter    int  `json:"resolve_after"`
+	ResolveMessage  Text `json:"resolve_message,omitempty"`
+}
+
+type RoomTimer struct {
+	RoomID          string     `json:"room_id"`
+	LastActivity    time.Time  `json:"last_activity"`
+	RemindAt        *time.Time `json:"remind_at,omitempty"`
+	ReminderMessage string     `json:"reminder_message,omitempty"`
+	ResolveAt       *time.Time `json:"resolve_at,omitempty"`
+	ResolveMessage  string     `json:"resolve_message,omitempty"`
+}
+
+type Rule struct {
+	Field    string   `json:"field"`
+	Operator string   `json:"operator"`
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+type FlowContext struct {
+	Attributes map[string]string
+	Now        time.Time
+}
+
+type BotSession struct {
+	Layer     string            `json:"layer"`
+	Locale    string            `json:"locale,omitempty"`
+	Page      int               `json:"page,omitempty"`
+	Variables map[string]string `json:"variables"`
+	Options   *OptionSnapshot   `json:"options,omitempty"`
+	Attempts  int               `json:"attempts,omitempty"`
+}
+
+type LayerVersion struct {
+	Version    int    `json:"version"`
+	Uploader   string `json:"uploader"`
+	UploadedAt string `json:"uploaded_at"`
+	Checksum   string `json:"checksum"`
+	Active     bool   `json:"active"`
+}
+
+type LayerDiff struct {
+	ID     string   `json:"id"`
+	Change string   `json:"change"`
+	Fields []string `json:"fields,omitempty"`
 }
@@ -54,16 +54,25 @@ type Option struct {
 }
 
 type BotRequestBody struct {
-	AdminEmail string `json:"sender_email"`
-	Message    string `json:"message"`
-	Type       string `json:"type"`
-	RoomID     string `json:"room_id"`
+	AdminEmail string      `json:"sender_email"`
+	Message    string      `json:"message"`
+	Type       string      `json:"type"`
+	RoomID     string      `json:"room_id"`
+	Payload    interface{} `json:"payload,omitempty"`
+}
+
+type OutboundMessage struct {
+	Type    string
+	Payload interface{}
 }
 
 type Draft struct {
-	Room    *WebhookRequest
-	Layer   Layer
-	Message string
+	Room           *WebhookRequest
+	Layer          Layer
+	Message        string
+	Outbound       *OutboundMessage
+	Tags           []string
+	AdditionalInfo map[string]string
 }
 
 type QismoRoomInfo struct {
@@ -77,6 +86,20 @@ type QismoRoomInfo struct {
 	Status int `json:"status"`
 }
 
+type UserInfoResponse struct {
+	Data struct {
+		Extras struct {
+			UserProperties []UserProperty `json:"user_properties"`
+		} `json:"extras"`
+	} `json:"data"`
+	Status int `json:"status"`
+}
+
+type UserProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
 type AgentsResponse struct {
 	Data struct {
 		Agents []Agent `json:"agents"`
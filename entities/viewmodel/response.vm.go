@@ -3,3 +3,24 @@ package viewmodel
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
+
+type LayerError struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type LayerValidationResponse struct {
+	Message string       `json:"message"`
+	Errors  []LayerError `json:"errors"`
+}
+
+type LayerUploadResponse struct {
+	Message string       `json:"message"`
+	Errors  []LayerError `json:"errors"`
+	Version LayerVersion `json:"version"`
+}
+
+type LayerTestResponse struct {
+	Message string           `json:"message"`
+	Results []FlowTestResult `json:"results"`
+}
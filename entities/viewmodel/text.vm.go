@@ -0,0 +1,81 @@
+package viewmodel
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+type Text map[string]string
+
+func NewText(text string) Text {
+	return Text{"": text}
+}
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	var plain string
+	if err := json.Unmarshal(data, &plain); err == nil {
+		*t = NewText(plain)
+		return nil
+	}
+
+	var localized map[string]string
+	if err := json.Unmarshal(data, &localized); err != nil {
+		return err
+	}
+	*t = localized
+
+	return nil
+}
+
+func (t Text) MarshalJSON() ([]byte, error) {
+	if plain, exist := t[""]; exist && len(t) == 1 {
+		return json.Marshal(plain)
+	}
+
+	return json.Marshal(map[string]string(t))
+}
+
+func (t Text) Get(locale string, fallback string) string {
+	if text, exist := t[locale]; exist {
+		return text
+	}
+
+	if text, exist := t[fallback]; exist {
+		return text
+	}
+
+	if text, exist := t[""]; exist {
+		return text
+	}
+
+	for _, value := range t.Values() {
+		return value
+	}
+
+	return ""
+}
+
+func (t Text) Values() []string {
+	var locales []string
+	for locale := range t {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	var values []string
+	for _, locale := range locales {
+		values = append(values, t[locale])
+	}
+
+	return values
+}
+
+func (t Text) IsEmpty() bool {
+	for _, value := range t {
+		if len(value) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bot-routing-engine/commands"
 	"bot-routing-engine/entities"
 	"bot-routing-engine/routes"
 	"bot-routing-engine/utils/logger"
@@ -23,6 +24,13 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && commands.Exist(os.Args[1]) {
+		if err := commands.Run(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	e := echo.New()
 
 	e.Use(middleware.Logger())
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/utils/logger"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ActionRepository interface {
+	Call(request viewmodel.HTTPRequest) (viewmodel.HTTPResponse, error)
+}
+
+type actionRepository struct {
+	timeout       time.Duration
+	outbondLogger *log.Logger
+}
+
+func NewActionRepository(outbondLogger *log.Logger) *actionRepository {
+	timeout, err := strconv.Atoi(os.Getenv("HTTP_ACTION_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 10
+	}
+
+	return &actionRepository{
+		timeout:       time.Duration(timeout) * time.Second,
+		outbondLogger: outbondLogger,
+	}
+}
+
+func (r *actionRepository) Call(request viewmodel.HTTPRequest) (viewmodel.HTTPResponse, error) {
+	method := strings.ToUpper(request.Method)
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	timeout := r.timeout
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	req, err := http.NewRequest(method, request.URL, strings.NewReader(request.Body))
+	if err != nil {
+		return viewmodel.HTTPResponse{}, err
+	}
+
+	if len(request.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return viewmodel.HTTPResponse{}, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return viewmodel.HTTPResponse{}, err
+	}
+
+	if r.outbondLogger != nil {
+		logger.WriteOutbondLog(r.outbondLogger, res, string(body), request.Body)
+	}
+
+	return viewmodel.HTTPResponse{Status: res.StatusCode, Body: body}, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	sessionBucket = []byte("sessions")
+	seenBucket    = []byte("seen")
+)
+
+type boltSessionStore struct {
+	db        *bolt.DB
+	lastSweep time.Time
+}
+
+func NewBoltSessionStore(path string) (*boltSessionStore, error) {
+	if len(path) == 0 {
+		path = "./data/sessions.db"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(sessionBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(seenBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltSessionStore{db: db}, nil
+}
+
+func (s *boltSessionStore) Load(roomID string) (viewmodel.BotSession, bool, error) {
+	var session viewmodel.BotSession
+	var exist bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(sessionBucket).Get([]byte(roomID))
+		if content == nil {
+			return nil
+		}
+		exist = true
+
+		return json.Unmarshal(content, &session)
+	})
+
+	return session, exist, err
+}
+
+func (s *boltSessionStore) Save(roomID string, session viewmodel.BotSession, messageID string, ttl time.Duration) error {
+	content, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(sessionBucket).Put([]byte(roomID), content); err != nil {
+			return err
+		}
+		if len(messageID) == 0 {
+			return nil
+		}
+
+		return s.markSeen(tx.Bucket(seenBucket), roomID+"/"+messageID, ttl)
+	})
+}
+
+func (s *boltSessionStore) Delete(roomID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionBucket).Delete([]byte(roomID))
+	})
+}
+
+func (s *boltSessionStore) Seen(roomID string, messageID string) (bool, error) {
+	var seen bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		expiry := tx.Bucket(seenBucket).Get([]byte(roomID + "/" + messageID))
+		seen = expiry != nil && time.Now().Before(boltExpiry(expiry))
+
+		return nil
+	})
+
+	return seen, err
+}
+
+// markSeen stores the expiry of a message ID. Expired IDs are swept at most
+// once a minute, inside the same write transaction that marks a new one.
+func (s *boltSessionStore) markSeen(bucket *bolt.Bucket, key string, ttl time.Duration) error {
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= time.Minute {
+		s.lastSweep = now
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if !now.Before(boltExpiry(v)) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return bucket.Put([]byte(key), []byte(strconv.FormatInt(now.Add(ttl).UnixNano(), 10)))
+}
+
+func boltExpiry(value []byte) time.Time {
+	expiry, _ := strconv.ParseInt(string(value), 10, 64)
+	return time.Unix(0, expiry)
+}
+
+func (s *boltSessionStore) Close() error {
+	return s.db.Close()
+}
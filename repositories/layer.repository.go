@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var layerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type LayerRepository interface {
+	ActivePath(name string) string
+	Versions(name string) ([]viewmodel.LayerVersion, error)
+	ReadVersion(name string, version int) ([]byte, error)
+	CreateVersion(name string, content []byte, uploader string) (viewmodel.LayerVersion, error)
+	Activate(name string, version int) (viewmodel.LayerVersion, error)
+}
+
+type layerRepository struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewLayerRepository() *layerRepository {
+	return &layerRepository{dir: "./layer"}
+}
+
+func (r *layerRepository) ActivePath(name string) string {
+	return filepath.Join(r.dir, name+".json")
+}
+
+func (r *layerRepository) versionDir(name string) string {
+	return filepath.Join(r.dir, "versions", name)
+}
+
+func (r *layerRepository) versionPath(name string, version int) string {
+	return filepath.Join(r.versionDir(name), fmt.Sprintf("%d.json", version))
+}
+
+func (r *layerRepository) Versions(name string) ([]viewmodel.LayerVersion, error) {
+	if !layerNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid layer name %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.readVersions(name)
+}
+
+func (r *layerRepository) ReadVersion(name string, version int) ([]byte, error) {
+	if !layerNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid layer name %q", name)
+	}
+
+	content, err := ioutil.ReadFile(r.versionPath(name, version))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("version %d of layer %q does not exist", version, name)
+	}
+
+	return content, err
+}
+
+func (r *layerRepository) CreateVersion(name string, content []byte, uploader string) (viewmodel.LayerVersion, error) {
+	if !layerNamePattern.MatchString(name) {
+		return viewmodel.LayerVersion{}, fmt.Errorf("invalid layer name %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, err := r.readVersions(name)
+	if err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	// The layer that was active before versioning existed is kept as the first
+	// version, so the first upload can still be rolled back to it.
+	if len(versions) == 0 {
+		versions, err = r.importActive(name)
+		if err != nil {
+			return viewmodel.LayerVersion{}, err
+		}
+	}
+
+	version := newLayerVersion(len(versions)+1, content, uploader, time.Now())
+	if err = r.storeVersion(name, append(versions, version), content); err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	return version, nil
+}
+
+func (r *layerRepository) importActive(name string) ([]viewmodel.LayerVersion, error) {
+	info, err := os.Stat(r.ActivePath(name))
+	if os.IsNotExist(err) {
+		return []viewmodel.LayerVersion{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(r.ActivePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	version := newLayerVersion(1, content, "", info.ModTime())
+	version.Active = true
+
+	versions := []viewmodel.LayerVersion{version}
+	if err = r.storeVersion(name, versions, content); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// storeVersion writes the content of the last version in versions, then the
+// version list itself.
+func (r *layerRepository) storeVersion(name string, versions []viewmodel.LayerVersion, content []byte) error {
+	if err := os.MkdirAll(r.versionDir(name), 0700); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(r.versionPath(name, versions[len(versions)-1].Version), content); err != nil {
+		return err
+	}
+
+	return r.writeVersions(name, versions)
+}
+
+func newLayerVersion(number int, content []byte, uploader string, uploadedAt time.Time) viewmodel.LayerVersion {
+	checksum := sha256.Sum256(content)
+
+	return viewmodel.LayerVersion{
+		Version:    number,
+		Uploader:   uploader,
+		UploadedAt: uploadedAt.Format(time.RFC3339),
+		Checksum:   hex.EncodeToString(checksum[:]),
+	}
+}
+
+func (r *layerRepository) Activate(name string, version int) (viewmodel.LayerVersion, error) {
+	if !layerNamePattern.MatchString(name) {
+		return viewmodel.LayerVersion{}, fmt.Errorf("invalid layer name %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, err := r.readVersions(name)
+	if err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	if version <= 0 || version > len(versions) {
+		return viewmodel.LayerVersion{}, fmt.Errorf("version %d of layer %q does not exist", version, name)
+	}
+
+	content, err := ioutil.ReadFile(r.versionPath(name, version))
+	if err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	checksum := sha256.Sum256(content)
+	if hex.EncodeToString(checksum[:]) != versions[version-1].Checksum {
+		return viewmodel.LayerVersion{}, errors.New("layer version checksum mismatch")
+	}
+
+	if err = writeFileAtomic(r.ActivePath(name), content); err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	for i := range versions {
+		versions[i].Active = versions[i].Version == version
+	}
+
+	if err = r.writeVersions(name, versions); err != nil {
+		return viewmodel.LayerVersion{}, err
+	}
+
+	return versions[version-1], nil
+}
+
+func (r *layerRepository) readVersions(name string) ([]viewmodel.LayerVersion, error) {
+	content, err := ioutil.ReadFile(filepath.Join(r.versionDir(name), "versions.json"))
+	if os.IsNotExist(err) {
+		return []viewmodel.LayerVersion{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []viewmodel.LayerVersion
+	if err = json.Unmarshal(content, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (r *layerRepository) writeVersions(name string, versions []viewmodel.LayerVersion) error {
+	content, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(r.versionDir(name), "versions.json"), content)
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import "sync"
+
+type LockRepository interface {
+	Lock(key string) (unlock func(), err error)
+}
+
+// localLockRepository hands out the lock for a key strictly in the order
+// Lock was called, so queued messages of a room are processed as they
+// arrived while other keys are never blocked.
+type localLockRepository struct {
+	queues map[string][]chan struct{}
+	mu     sync.Mutex
+}
+
+func NewLocalLockRepository() *localLockRepository {
+	return &localLockRepository{queues: make(map[string][]chan struct{})}
+}
+
+func (r *localLockRepository) Lock(key string) (func(), error) {
+	turn := make(chan struct{})
+
+	r.mu.Lock()
+	queue, busy := r.queues[key]
+	r.queues[key] = append(queue, turn)
+	r.mu.Unlock()
+
+	if busy {
+		<-turn
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { r.release(key) })
+	}, nil
+}
+
+func (r *localLockRepository) release(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queue := r.queues[key][1:]
+	if len(queue) == 0 {
+		delete(r.queues, key)
+		return
+	}
+
+	r.queues[key] = queue
+	close(queue[0])
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalLockIsFIFO(t *testing.T) {
+	locks := NewLocalLockRepository()
+	unlock, _ := locks.Lock("room")
+
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			unlock, _ := locks.Lock("room")
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			unlock()
+		}(i)
+		waitQueued(t, locks, "room", i+2)
+	}
+
+	unlock()
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("lock was not handed out in arrival order: %v", order)
+		}
+	}
+	if len(locks.queues) != 0 {
+		t.Errorf("released keys should be removed, %d left", len(locks.queues))
+	}
+}
+
+func TestLocalLockKeysAreIndependent(t *testing.T) {
+	locks := NewLocalLockRepository()
+	unlock, _ := locks.Lock("a")
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		unlock, _ := locks.Lock("b")
+		unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a held lock blocked another key")
+	}
+}
+
+func TestLocalUnlockTwice(t *testing.T) {
+	locks := NewLocalLockRepository()
+	first, _ := locks.Lock("room")
+
+	acquired := make(chan func())
+	go func() {
+		unlock, _ := locks.Lock("room")
+		acquired <- unlock
+	}()
+	waitQueued(t, locks, "room", 2)
+
+	first()
+	first()
+	second := <-acquired
+
+	if queue := len(locks.queues["room"]); queue != 1 {
+		t.Fatalf("a repeated unlock released another holder, queue length %d", queue)
+	}
+	second()
+}
+
+func waitQueued(t *testing.T, locks *localLockRepository, key string, length int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		locks.mu.Lock()
+		queued := len(locks.queues[key])
+		locks.mu.Unlock()
+		if queued >= length {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("expected %d queued lockers for %s", length, key)
+}
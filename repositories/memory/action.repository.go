@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type actionRepository struct {
+	responses []viewmodel.FlowTestResponse
+}
+
+func NewActionRepository(responses []viewmodel.FlowTestResponse) *actionRepository {
+	return &actionRepository{responses}
+}
+
+// Call never leaves the process, requests without a canned response fail the
+// same way an unreachable endpoint would.
+func (r *actionRepository) Call(request viewmodel.HTTPRequest) (viewmodel.HTTPResponse, error) {
+	method := strings.ToUpper(request.Method)
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	for _, response := range r.responses {
+		if len(response.Method) > 0 && !strings.EqualFold(response.Method, method) {
+			continue
+		}
+
+		matched := response.URL == request.URL
+		if prefix := strings.TrimSuffix(response.URL, "*"); prefix != response.URL {
+			matched = strings.HasPrefix(request.URL, prefix)
+		}
+		if !matched {
+			continue
+		}
+
+		status := response.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		return viewmodel.HTTPResponse{Status: status, Body: []byte(response.Body)}, nil
+	}
+
+	return viewmodel.HTTPResponse{}, fmt.Errorf("no canned response for %s %s", method, request.URL)
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"strconv"
+)
+
+type multichannelRepository struct {
+	recorder   *Recorder
+	officeHour viewmodel.OfficeHourResp
+	divisions  []string
+}
+
+func NewMultichannelRepository(recorder *Recorder, officeHour viewmodel.OfficeHourResp, divisions []string) *multichannelRepository {
+	return &multichannelRepository{recorder, officeHour, divisions}
+}
+
+func (r *multichannelRepository) SendBotMessage(roomID string, message string) error {
+	return r.SendBotPayload(roomID, message, "text", nil)
+}
+
+func (r *multichannelRepository) SendBotPayload(roomID string, message string, messageType string, payload interface{}) error {
+	kind := EventMessage
+	if payload != nil {
+		kind = EventPayload
+	}
+
+	r.recorder.Record(Event{Kind: kind, RoomID: roomID, Text: message, Type: messageType, Data: payload})
+
+	return nil
+}
+
+func (r *multichannelRepository) GetAllAgents(limit int) (viewmodel.AgentsResponse, error) {
+	var agents viewmodel.AgentsResponse
+	agents.Data.Agents = []viewmodel.Agent{r.agent(0)}
+
+	return agents, nil
+}
+
+func (r *multichannelRepository) OfficeHour() (viewmodel.OfficeHourResp, error) {
+	return r.officeHour, nil
+}
+
+func (r *multichannelRepository) GetAllDivisions() (viewmodel.Divisions, error) {
+	var divisions viewmodel.Divisions
+	for i, name := range r.divisions {
+		divisions.Data = append(divisions.Data, viewmodel.Division{ID: i + 1, Name: name})
+	}
+
+	return divisions, nil
+}
+
+func (r *multichannelRepository) GetAgentsByDivision(divisionID string) (viewmodel.AgentsByDivision, error) {
+	ID, _ := strconv.Atoi(divisionID)
+
+	var agents viewmodel.AgentsByDivision
+	agents.Data = []viewmodel.Agent{r.agent(ID)}
+
+	return agents, nil
+}
+
+func (r *multichannelRepository) agent(divisionID int) viewmodel.Agent {
+	name := "Agent"
+	if divisionID > 0 && divisionID <= len(r.divisions) {
+		name = "Agent " + r.divisions[divisionID-1]
+	}
+
+	return viewmodel.Agent{
+		ID:          divisionID,
+		Name:        name,
+		IsAvailable: true,
+		TypeStr:     "agent",
+	}
+}
+
+func (r *multichannelRepository) Division(agentID string) string {
+	ID, _ := strconv.Atoi(agentID)
+	if ID > 0 && ID <= len(r.divisions) {
+		return r.divisions[ID-1]
+	}
+
+	return ""
+}
@@ -0,0 +1,47 @@
+package memory
+
+import "sync"
+
+const (
+	EventMessage  = "message"
+	EventPayload  = "payload"
+	EventTag      = "tag"
+	EventUserInfo = "user_info"
+	EventResolve  = "resolve"
+	EventHandover = "handover"
+	EventBot      = "bot"
+)
+
+type Event struct {
+	Kind   string
+	RoomID string
+	Text   string
+	Type   string
+	Data   interface{}
+}
+
+type Recorder struct {
+	events []Event
+	mu     sync.Mutex
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *Recorder) Drain() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events
+	r.events = nil
+
+	return events
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"bot-routing-engine/entities"
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"sync"
+)
+
+type roomRepository struct {
+	recorder  *Recorder
+	divisions func(agentID string) string
+	rooms     map[string]string
+	userInfo  map[string][]viewmodel.UserProperty
+	mu        sync.Mutex
+}
+
+func NewRoomRepository(recorder *Recorder, divisions func(agentID string) string) *roomRepository {
+	return &roomRepository{
+		recorder:  recorder,
+		divisions: divisions,
+		rooms:     make(map[string]string),
+		userInfo:  make(map[string][]viewmodel.UserProperty),
+	}
+}
+
+func (r *roomRepository) SDKGetRoomInfo(ID string) (entities.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	options, exist := r.rooms[ID]
+	if !exist {
+		options = "{}"
+	}
+
+	var room entities.Room
+	room.Results.Rooms = []entities.Rooms{{ID: ID, Options: options}}
+
+	return room, nil
+}
+
+func (r *roomRepository) StateExist(room entities.Room) bool {
+	var roomOptions map[string]json.RawMessage
+	json.Unmarshal([]byte(room.Results.Rooms[0].Options), &roomOptions)
+
+	_, ok := roomOptions["bot_layer"]
+
+	return ok
+}
+
+func (r *roomRepository) UpdateRoom(ID string, options string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rooms[ID] = options
+
+	return nil
+}
+
+func (r *roomRepository) Resolve(ID string, lastCommentID string) error {
+	r.recorder.Record(Event{Kind: EventResolve, RoomID: ID})
+
+	return nil
+}
+
+func (r *roomRepository) QismoRoomInfo(ID string) (viewmodel.QismoRoomInfo, error) {
+	var room viewmodel.QismoRoomInfo
+	room.Data.CustomerRoom.ID = 1
+	room.Data.CustomerRoom.Name = ID
+
+	return room, nil
+}
+
+func (r *roomRepository) ResetBotLayers(ID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var roomOptions map[string]interface{}
+	json.Unmarshal([]byte(r.rooms[ID]), &roomOptions)
+
+	delete(roomOptions, "bot_layer")
+	delete(roomOptions, "bot_session")
+	options, err := json.Marshal(roomOptions)
+	if err != nil {
+		return err
+	}
+	r.rooms[ID] = string(options)
+
+	return nil
+}
+
+func (r *roomRepository) TagRoom(ID string, tag string) error {
+	r.recorder.Record(Event{Kind: EventTag, RoomID: ID, Text: tag})
+
+	return nil
+}
+
+func (r *roomRepository) GetUserInfo(ID string) ([]viewmodel.UserProperty, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]viewmodel.UserProperty(nil), r.userInfo[ID]...), nil
+}
+
+func (r *roomRepository) UpdateUserInfo(ID string, properties []viewmodel.UserProperty) error {
+	r.mu.Lock()
+	previous := make(map[string]string)
+	for _, property := range r.userInfo[ID] {
+		previous[property.Key] = property.Value
+	}
+	r.userInfo[ID] = properties
+	r.mu.Unlock()
+
+	for _, property := range properties {
+		if value, exist := previous[property.Key]; !exist || value != property.Value {
+			r.recorder.Record(Event{Kind: EventUserInfo, RoomID: ID, Type: property.Key, Text: property.Value})
+		}
+	}
+
+	return nil
+}
+
+func (r *roomRepository) AssignAgent(ID string, agentID string) error {
+	r.recorder.Record(Event{Kind: EventHandover, RoomID: ID, Text: r.divisions(agentID)})
+
+	return nil
+}
+
+func (r *roomRepository) ToggleBotInRoom(ID string, activate bool) error {
+	r.recorder.Record(Event{Kind: EventBot, RoomID: ID, Data: activate})
+
+	return nil
+}
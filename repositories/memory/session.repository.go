@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type sessionStore struct {
+	sessions map[string][]byte
+	seen     map[string]time.Time
+	mu       sync.Mutex
+}
+
+func NewSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string][]byte),
+		seen:     make(map[string]time.Time),
+	}
+}
+
+// Sessions are kept serialized so callers never share maps or slices with
+// the store, the same as they would with a persistent backend.
+func (s *sessionStore) Load(roomID string) (viewmodel.BotSession, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var session viewmodel.BotSession
+	content, exist := s.sessions[roomID]
+	if !exist {
+		return session, false, nil
+	}
+
+	err := json.Unmarshal(content, &session)
+	return session, true, err
+}
+
+func (s *sessionStore) Save(roomID string, session viewmodel.BotSession, messageID string, ttl time.Duration) error {
+	content, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[roomID] = content
+	if len(messageID) > 0 {
+		s.markSeen(roomID+"/"+messageID, ttl)
+	}
+
+	return nil
+}
+
+func (s *sessionStore) Delete(roomID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, roomID)
+	return nil
+}
+
+func (s *sessionStore) Seen(roomID string, messageID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, exist := s.seen[roomID+"/"+messageID]
+	return exist && time.Now().Before(expiry), nil
+}
+
+func (s *sessionStore) markSeen(key string, ttl time.Duration) {
+	now := time.Now()
+	for seenKey, expiry := range s.seen {
+		if !now.Before(expiry) {
+			delete(s.seen, seenKey)
+		}
+	}
+	s.seen[key] = now.Add(ttl)
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"sync"
+)
+
+type timerRepository struct {
+	timers map[string]viewmodel.RoomTimer
+	mu     sync.Mutex
+}
+
+func NewTimerRepository() *timerRepository {
+	return &timerRepository{timers: make(map[string]viewmodel.RoomTimer)}
+}
+
+func (r *timerRepository) All() ([]viewmodel.RoomTimer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var timers []viewmodel.RoomTimer
+	for _, timer := range r.timers {
+		timers = append(timers, timer)
+	}
+
+	return timers, nil
+}
+
+func (r *timerRepository) Get(roomID string) (viewmodel.RoomTimer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timer, exist := r.timers[roomID]
+	return timer, exist
+}
+
+func (r *timerRepository) Save(timer viewmodel.RoomTimer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timers[timer.RoomID] = timer
+	return nil
+}
+
+func (r *timerRepository) Delete(roomID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.timers, roomID)
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 
 type MultichannelRepository interface {
 	SendBotMessage(roomID string, message string) error
+	SendBotPayload(roomID string, message string, messageType string, payload interface{}) error
 	GetAllAgents(limit int) (viewmodel.AgentsResponse, error)
 	OfficeHour() (viewmodel.OfficeHourResp, error)
 	GetAllDivisions() (viewmodel.Divisions, error)
@@ -36,13 +37,18 @@ func NewMultichannelRepository(multichannel *entities.Multichannel, outbondLogge
 }
 
 func (r *multichannelRepository) SendBotMessage(roomID string, message string) error {
+	return r.SendBotPayload(roomID, message, "text", nil)
+}
+
+func (r *multichannelRepository) SendBotPayload(roomID string, message string, messageType string, botPayload interface{}) error {
 	url := fmt.Sprintf("%s/%s/bot", r.qismoURL, r.multichannel.GetAppID())
 	method := "POST"
 	payload := viewmodel.BotRequestBody{
 		AdminEmail: r.multichannel.GetAdminEmail(),
 		Message:    message,
-		Type:       "text",
+		Type:       messageType,
 		RoomID:     roomID,
+		Payload:    botPayload,
 	}
 	reqBody, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisLockPrefix = "bot-routing-engine:lock:"
+	redisTimersKey  = "bot-routing-engine:timers"
+	redisLockPoll   = 50 * time.Millisecond
+)
+
+// Only the holder of the token may extend or release a lease.
+var (
+	redisRenewScript   = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`)
+	redisReleaseScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`)
+)
+
+// redisLockRepository serializes a room across instances with a leased
+// Redis key. Waiters of the same instance queue on a local lock first, so
+// ordering within an instance is kept and only one of them polls Redis.
+type redisLockRepository struct {
+	local  *localLockRepository
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisClient(rawURL string) (*redis.Client, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return redis.NewClient(options), nil
+}
+
+func NewRedisLockRepository(client *redis.Client, ttl time.Duration) *redisLockRepository {
+	return &redisLockRepository{
+		local:  NewLocalLockRepository(),
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (r *redisLockRepository) Lock(key string) (func(), error) {
+	unlockLocal, _ := r.local.Lock(key)
+
+	token, err := redisToken()
+	if err != nil {
+		unlockLocal()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.ttl)
+	defer cancel()
+
+	key = redisLockPrefix + key
+	for {
+		acquired, err := r.client.SetNX(ctx, key, token, r.ttl).Result()
+		if err != nil {
+			unlockLocal()
+			return nil, fmt.Errorf("lock %s: %w", key, err)
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			unlockLocal()
+			return nil, fmt.Errorf("timed out waiting for lock %s", key)
+		case <-time.After(redisLockPoll):
+		}
+	}
+
+	stop := make(chan struct{})
+	go r.renew(key, token, stop)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			redisReleaseScript.Run(context.Background(), r.client, []string{key}, token)
+			unlockLocal()
+		})
+	}, nil
+}
+
+func (r *redisLockRepository) renew(key string, token string, stop chan struct{}) {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			redisRenewScript.Run(context.Background(), r.client, []string{key}, token, r.ttl.Milliseconds())
+		case <-stop:
+			return
+		}
+	}
+}
+
+func redisToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// redisTimerRepository shares room timers between instances, each room is a
+// field of a single hash so the scheduler of any instance sees every timer.
+type redisTimerRepository struct {
+	client *redis.Client
+}
+
+func NewRedisTimerRepository(client *redis.Client) *redisTimerRepository {
+	return &redisTimerRepository{client}
+}
+
+func (r *redisTimerRepository) All() ([]viewmodel.RoomTimer, error) {
+	values, err := r.client.HGetAll(context.Background(), redisTimersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	timers := make([]viewmodel.RoomTimer, 0, len(values))
+	for _, content := range values {
+		var timer viewmodel.RoomTimer
+		if err = json.Unmarshal([]byte(content), &timer); err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer)
+	}
+
+	return timers, nil
+}
+
+func (r *redisTimerRepository) Get(roomID string) (viewmodel.RoomTimer, bool) {
+	var timer viewmodel.RoomTimer
+
+	content, err := r.client.HGet(context.Background(), redisTimersKey, roomID).Result()
+	if err != nil {
+		return timer, false
+	}
+
+	if err = json.Unmarshal([]byte(content), &timer); err != nil {
+		return timer, false
+	}
+
+	return timer, true
+}
+
+func (r *redisTimerRepository) Save(timer viewmodel.RoomTimer) error {
+	content, err := json.Marshal(timer)
+	if err != nil {
+		return err
+	}
+
+	return r.client.HSet(context.Background(), redisTimersKey, timer.RoomID, content).Err()
+}
+
+func (r *redisTimerRepository) Delete(roomID string) error {
+	return r.client.HDel(context.Background(), redisTimersKey, roomID).Err()
+}
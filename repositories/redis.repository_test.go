@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func redisLocks(t *testing.T, server *miniredis.Miniredis, ttl time.Duration) *redisLockRepository {
+	t.Helper()
+
+	client, err := NewRedisClient("redis://" + server.Addr() + "/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisLockRepository(client, ttl)
+}
+
+func TestRedisLockExcludesOtherInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	first := redisLocks(t, server, time.Second)
+	second := redisLocks(t, server, time.Second)
+
+	unlock, err := first.Lock("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := second.Lock("room")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		unlock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second instance acquired a held lock")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second instance did not acquire the released lock")
+	}
+
+	if server.Exists(redisLockPrefix + "room") {
+		t.Error("released lock left its key behind")
+	}
+}
+
+func TestRedisLockTimesOut(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.Set(redisLockPrefix+"room", "other")
+
+	locks := redisLocks(t, server, 200*time.Millisecond)
+	if _, err := locks.Lock("room"); err == nil {
+		t.Fatal("expected a timeout while another holder owns the lock")
+	}
+
+	// The local queue must be released after a failed attempt.
+	server.Del(redisLockPrefix + "room")
+	unlock, err := locks.Lock("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestRedisUnlockKeepsForeignLease(t *testing.T) {
+	server := miniredis.RunT(t)
+	locks := redisLocks(t, server, time.Second)
+
+	unlock, err := locks.Lock("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate the lease expiring and another instance taking over.
+	server.Set(redisLockPrefix+"room", "other")
+	unlock()
+
+	if value, _ := server.Get(redisLockPrefix + "room"); value != "other" {
+		t.Errorf("unlock released a lease it does not own, got %q", value)
+	}
+}
+
+func TestRedisLockRenewsLease(t *testing.T) {
+	server := miniredis.RunT(t)
+	locks := redisLocks(t, server, 300*time.Millisecond)
+
+	unlock, err := locks.Lock("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	server.FastForward(250 * time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	if ttl := server.TTL(redisLockPrefix + "room"); ttl <= 100*time.Millisecond {
+		t.Errorf("lease was not renewed while held, ttl %s", ttl)
+	}
+}
+
+func TestRedisTimersAreShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, _ := NewRedisClient("redis://" + server.Addr())
+	second, _ := NewRedisClient("redis://" + server.Addr())
+	defer first.Close()
+	defer second.Close()
+
+	now := time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC)
+	if err := NewRedisTimerRepository(first).Save(viewmodel.RoomTimer{RoomID: "room", LastActivity: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	timers := NewRedisTimerRepository(second)
+	timer, exist := timers.Get("room")
+	if !exist || !timer.LastActivity.Equal(now) {
+		t.Fatalf("timer saved by another instance was not found, got %+v", timer)
+	}
+
+	if err := timers.Delete("room"); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := NewRedisTimerRepository(first).All(); len(all) != 0 {
+		t.Errorf("deleted timer still listed: %+v", all)
+	}
+}
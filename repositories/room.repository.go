@@ -23,6 +23,8 @@ type RoomRepository interface {
 	QismoRoomInfo(ID string) (viewmodel.QismoRoomInfo, error)
 	ResetBotLayers(ID string) error
 	TagRoom(ID string, tag string) error
+	GetUserInfo(ID string) ([]viewmodel.UserProperty, error)
+	UpdateUserInfo(ID string, properties []viewmodel.UserProperty) error
 	AssignAgent(ID string, agentID string) error
 	ToggleBotInRoom(ID string, activate bool) error
 }
@@ -78,7 +80,7 @@ func (r *roomRepository) SDKGetRoomInfo(ID string) (entities.Room, error) {
 }
 
 func (r *roomRepository) StateExist(room entities.Room) bool {
-	var roomOptions map[string]string
+	var roomOptions map[string]json.RawMessage
 	json.Unmarshal([]byte(room.Results.Rooms[0].Options), &roomOptions)
 
 	_, ok := roomOptions["bot_layer"]
@@ -206,6 +208,7 @@ func (r *roomRepository) ResetBotLayers(ID string) error {
 	json.Unmarshal([]byte(roomInfo.Results.Rooms[0].Options), &roomOptions)
 
 	delete(roomOptions, "bot_layer")
+	delete(roomOptions, "bot_session")
 	options, err := json.Marshal(roomOptions)
 	if err != nil {
 		return err
@@ -225,7 +228,7 @@ func (r *roomRepository) TagRoom(ID string, tag string) error {
 
 	formData := url.Values{}
 	formData.Set("room_id", ID)
-	formData.Set("tag", os.Getenv("AUTO_RESOLVE_TAG"))
+	formData.Set("tag", tag)
 
 	client := &http.Client{}
 
@@ -255,6 +258,90 @@ func (r *roomRepository) TagRoom(ID string, tag string) error {
 	return nil
 }
 
+func (r *roomRepository) GetUserInfo(ID string) ([]viewmodel.UserProperty, error) {
+	url := fmt.Sprintf("%s/api/v1/qiscus/room/%s/user_info", r.qismoUrl, ID)
+	method := "GET"
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", r.multichannel.GetToken())
+	req.Header.Set("Qiscus-App-Id", r.multichannel.GetAppID())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WriteOutbondLog(r.outbondLogger, resp, string(body), "")
+
+	// Failing here matters, the properties read are written back in full and
+	// an empty read would erase the customer's existing additional info.
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("get user info failed with status %d", resp.StatusCode)
+	}
+
+	var userInfo viewmodel.UserInfoResponse
+	if err = json.Unmarshal(body, &userInfo); err != nil {
+		return nil, fmt.Errorf("get user info: %w", err)
+	}
+
+	return userInfo.Data.Extras.UserProperties, nil
+}
+
+func (r *roomRepository) UpdateUserInfo(ID string, properties []viewmodel.UserProperty) error {
+	url := fmt.Sprintf("%s/api/v1/qiscus/room/%s/user_info", r.qismoUrl, ID)
+	method := "POST"
+	payload, err := json.Marshal(map[string][]viewmodel.UserProperty{
+		"user_properties": properties,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", r.multichannel.GetToken())
+	req.Header.Set("Qiscus-App-Id", r.multichannel.GetAppID())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	logger.WriteOutbondLog(r.outbondLogger, resp, string(body), string(payload))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("update user info failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 func (r *roomRepository) AssignAgent(ID string, agentID string) error {
 	apiUrl := fmt.Sprintf("%s/api/v1/admin/service/assign_agent", r.qismoUrl)
 	method := "POST"
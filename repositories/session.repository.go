@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Save marks messageID, when set, as seen for ttl in the same write as the
+// session, so a retried message is never replayed on top of its saved state.
+type SessionStore interface {
+	Load(roomID string) (viewmodel.BotSession, bool, error)
+	Save(roomID string, session viewmodel.BotSession, messageID string, ttl time.Duration) error
+	Delete(roomID string) error
+	Seen(roomID string, messageID string) (bool, error)
+}
+
+// roomSessionStore keeps processed message IDs in the bot_seen room option,
+// mapped to their unix expiry. It outlives the session, so a retry of the
+// message that resolved the room is still recognised.
+type roomSessionStore struct {
+	roomRepository RoomRepository
+	options        map[string]string
+	mu             sync.Mutex
+}
+
+func NewRoomSessionStore(roomRepository RoomRepository) *roomSessionStore {
+	return &roomSessionStore{
+		roomRepository: roomRepository,
+		options:        make(map[string]string),
+	}
+}
+
+func (s *roomSessionStore) Load(roomID string) (viewmodel.BotSession, bool, error) {
+	var session viewmodel.BotSession
+
+	room, err := s.roomRepository.SDKGetRoomInfo(roomID)
+	if err != nil {
+		return session, false, err
+	}
+	if len(room.Results.Rooms) == 0 {
+		return session, false, nil
+	}
+
+	// Saves follow a load under the room lock, the options read here are
+	// reused so a save costs a single UpdateRoom call.
+	s.remember(roomID, room.Results.Rooms[0].Options)
+	if !s.roomRepository.StateExist(room) {
+		return session, false, nil
+	}
+
+	var roomOptions map[string]json.RawMessage
+	json.Unmarshal([]byte(room.Results.Rooms[0].Options), &roomOptions)
+	json.Unmarshal(roomOptions["bot_session"], &session)
+
+	// bot_layer is kept as the source of truth for the current node, legacy
+	// rooms store it as an index path which is passed on as is to be migrated.
+	var ID string
+	if err = json.Unmarshal(roomOptions["bot_layer"], &ID); err == nil {
+		session.Layer = ID
+	} else {
+		session.Layer = string(roomOptions["bot_layer"])
+	}
+
+	return session, true, nil
+}
+
+func (s *roomSessionStore) Save(roomID string, session viewmodel.BotSession, messageID string, ttl time.Duration) error {
+	options, err := s.roomOptions(roomID)
+	if err != nil {
+		return err
+	}
+
+	var roomOptions map[string]interface{}
+	json.Unmarshal([]byte(options), &roomOptions)
+	if roomOptions == nil {
+		roomOptions = make(map[string]interface{})
+	}
+
+	roomOptions["bot_layer"] = session.Layer
+	roomOptions["bot_session"] = session
+	if len(messageID) > 0 {
+		now := time.Now()
+		seen := roomSeen(options, now)
+		seen[messageID] = now.Add(ttl).Unix()
+		roomOptions["bot_seen"] = seen
+	}
+
+	roomOptionsJson, err := json.Marshal(roomOptions)
+	if err != nil {
+		return err
+	}
+
+	if err = s.roomRepository.UpdateRoom(roomID, string(roomOptionsJson)); err != nil {
+		s.forget(roomID)
+		return err
+	}
+	s.remember(roomID, string(roomOptionsJson))
+
+	return nil
+}
+
+func (s *roomSessionStore) Delete(roomID string) error {
+	s.forget(roomID)
+	return s.roomRepository.ResetBotLayers(roomID)
+}
+
+func (s *roomSessionStore) remember(roomID string, options string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.options[roomID] = options
+}
+
+func (s *roomSessionStore) remembered(roomID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	options, exist := s.options[roomID]
+	return options, exist
+}
+
+func (s *roomSessionStore) forget(roomID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.options, roomID)
+}
+
+func (s *roomSessionStore) Seen(roomID string, messageID string) (bool, error) {
+	options, err := s.roomOptions(roomID)
+	if err != nil {
+		return false, err
+	}
+
+	_, seen := roomSeen(options, time.Now())[messageID]
+	return seen, nil
+}
+
+// roomOptions returns the options read by the last load of the room, they are
+// only fetched again when the room was not loaded by this instance.
+func (s *roomSessionStore) roomOptions(roomID string) (string, error) {
+	if options, exist := s.remembered(roomID); exist {
+		return options, nil
+	}
+
+	room, err := s.roomRepository.SDKGetRoomInfo(roomID)
+	if err != nil || len(room.Results.Rooms) == 0 {
+		return "", err
+	}
+
+	return room.Results.Rooms[0].Options, nil
+}
+
+// roomSeen reads the unexpired message IDs of the bot_seen room option.
+func roomSeen(options string, now time.Time) map[string]int64 {
+	var roomOptions struct {
+		Seen map[string]int64 `json:"bot_seen"`
+	}
+	json.Unmarshal([]byte(options), &roomOptions)
+
+	seen := make(map[string]int64)
+	for ID, expiry := range roomOptions.Seen {
+		if now.Unix() < expiry {
+			seen[ID] = expiry
+		}
+	}
+
+	return seen
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var timerBucket = []byte("timers")
+
+type TimerRepository interface {
+	All() ([]viewmodel.RoomTimer, error)
+	Get(roomID string) (viewmodel.RoomTimer, bool)
+	Save(timer viewmodel.RoomTimer) error
+	Delete(roomID string) error
+}
+
+type timerRepository struct {
+	db *bolt.DB
+}
+
+func NewTimerRepository() (*timerRepository, error) {
+	path := "./data/timers.db"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(timerBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &timerRepository{db}, nil
+}
+
+func (r *timerRepository) All() ([]viewmodel.RoomTimer, error) {
+	var timers []viewmodel.RoomTimer
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(timerBucket).ForEach(func(key []byte, content []byte) error {
+			var timer viewmodel.RoomTimer
+			if err := json.Unmarshal(content, &timer); err != nil {
+				return err
+			}
+			timers = append(timers, timer)
+
+			return nil
+		})
+	})
+
+	return timers, err
+}
+
+func (r *timerRepository) Get(roomID string) (viewmodel.RoomTimer, bool) {
+	var timer viewmodel.RoomTimer
+	var exist bool
+
+	r.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(timerBucket).Get([]byte(roomID))
+		if content == nil {
+			return nil
+		}
+		exist = json.Unmarshal(content, &timer) == nil
+
+		return nil
+	})
+
+	return timer, exist
+}
+
+func (r *timerRepository) Save(timer viewmodel.RoomTimer) error {
+	content, err := json.Marshal(timer)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(timerBucket).Put([]byte(timer.RoomID), content)
+	})
+}
+
+func (r *timerRepository) Delete(roomID string) error {
+	// Rooms on untimed nodes are cleared on every message, skip the write
+	// transaction when there is nothing to delete.
+	if _, exist := r.Get(roomID); !exist {
+		return nil
+	}
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(timerBucket).Delete([]byte(roomID))
+	})
+}
@@ -5,7 +5,12 @@ import (
 	"bot-routing-engine/controllers/services"
 	"bot-routing-engine/entities"
 	"bot-routing-engine/repositories"
+	"bot-routing-engine/repositories/memory"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -28,15 +33,75 @@ func (r *route) RegisterRoute() {
 
 	roomRepo := repositories.NewRoomRepository(r.Multichannel, r.outbondLogger)
 	mulchanRepo := repositories.NewMultichannelRepository(r.Multichannel, r.outbondLogger)
+	layerRepo := repositories.NewLayerRepository()
+	actionRepo := repositories.NewActionRepository(r.outbondLogger)
+	sessionStore, err := r.sessionStore(roomRepo)
+	if err != nil {
+		r.e.Logger.Fatal(err)
+	}
+	lockRepo, timerRepo, err := r.roomCoordination()
+	if err != nil {
+		r.e.Logger.Fatal(err)
+	}
 
-	layerService := services.NewLayerService()
+	layerService := services.NewLayerService(mulchanRepo, layerRepo, actionRepo)
 	requestService := services.NewRequestService()
-	roomService := services.NewRoomService(mulchanRepo, roomRepo)
-	messageService := services.NewMessageService(mulchanRepo, *roomService)
+	roomService := services.NewRoomService(mulchanRepo, roomRepo, sessionStore, lockRepo, r.outbondLogger)
+	schedulerService := services.NewSchedulerService(timerRepo, roomService, r.outbondLogger)
+	messageService := services.NewMessageService(mulchanRepo, layerService, *roomService, schedulerService)
 
 	messageController := controllers.NewMessageController(layerService, requestService, messageService, roomService)
-	uploadController := controllers.NewUploadController()
+	uploadController := controllers.NewUploadController(layerService)
+	layerController := controllers.NewLayerController(layerService)
+
+	schedulerInterval, err := strconv.Atoi(os.Getenv("SCHEDULER_INTERVAL"))
+	if err != nil || schedulerInterval <= 0 {
+		schedulerInterval = 30
+	}
+	schedulerService.Start(time.Duration(schedulerInterval) * time.Second)
 
 	messageGroup.POST("/received", messageController.MessageReceived)
 	appGroup.POST("/upload", uploadController.Upload)
+	appGroup.GET("/layers/:name/versions", layerController.Versions)
+	appGroup.GET("/layers/:name/versions/diff", layerController.Diff)
+	appGroup.POST("/layers/:name/versions/:version/activate", layerController.Activate)
+	appGroup.GET("/layers/:name/graph", layerController.Graph)
+}
+
+func (r *route) sessionStore(roomRepo repositories.RoomRepository) (repositories.SessionStore, error) {
+	switch store := os.Getenv("SESSION_STORE"); store {
+	case "", "room":
+		return repositories.NewRoomSessionStore(roomRepo), nil
+	case "bolt":
+		return repositories.NewBoltSessionStore(os.Getenv("SESSION_STORE_PATH"))
+	case "memory":
+		return memory.NewSessionStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q", store)
+	}
+}
+
+// With ROOM_LOCK=redis the room timers move to redis as well, otherwise the
+// scheduler of one instance would never see the activity handled by another.
+func (r *route) roomCoordination() (repositories.LockRepository, repositories.TimerRepository, error) {
+	switch lock := os.Getenv("ROOM_LOCK"); lock {
+	case "", "local":
+		timerRepo, err := repositories.NewTimerRepository()
+		if err != nil {
+			return nil, nil, err
+		}
+		return repositories.NewLocalLockRepository(), timerRepo, nil
+	case "redis":
+		client, err := repositories.NewRedisClient(os.Getenv("REDIS_URL"))
+		if err != nil {
+			return nil, nil, err
+		}
+		ttl, err := strconv.Atoi(os.Getenv("ROOM_LOCK_TTL"))
+		if err != nil || ttl <= 0 {
+			ttl = 30
+		}
+		return repositories.NewRedisLockRepository(client, time.Duration(ttl)*time.Second), repositories.NewRedisTimerRepository(client), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown room lock %q", lock)
+	}
 }
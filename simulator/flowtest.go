@@ -0,0 +1,255 @@
+package simulator
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories/memory"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultClock = "2021-01-04 10:00"
+
+func ParseSuite(filename string, content []byte) (viewmodel.FlowTestSuite, error) {
+	var suite viewmodel.FlowTestSuite
+
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = json.Unmarshal(content, &suite)
+	} else {
+		err = yaml.UnmarshalStrict(content, &suite)
+	}
+	if err != nil {
+		return suite, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+
+	for i, test := range suite.Tests {
+		if len(test.Name) == 0 {
+			suite.Tests[i].Name = fmt.Sprintf("%s #%d", filepath.Base(filename), i+1)
+		}
+		suite.Tests[i].Responses = append(test.Responses[:len(test.Responses):len(test.Responses)], suite.Responses...)
+	}
+
+	return suite, nil
+}
+
+func RunTests(layer viewmodel.Layer, tests []viewmodel.FlowTest) []viewmodel.FlowTestResult {
+	var results []viewmodel.FlowTestResult
+	for _, test := range tests {
+		results = append(results, RunTest(layer, test))
+	}
+
+	return results
+}
+
+func RunTest(layer viewmodel.Layer, test viewmodel.FlowTest) viewmodel.FlowTestResult {
+	result := viewmodel.FlowTestResult{Name: test.Name}
+
+	config := Config{
+		Clock:         test.Clock,
+		Source:        test.Source,
+		Channel:       test.Channel,
+		ChannelID:     test.ChannelID,
+		CustomerName:  test.Customer,
+		CustomerEmail: test.Email,
+		OfficeHours:   test.OfficeHours,
+		Actions:       memory.NewActionRepository(test.Responses),
+	}
+	if len(config.Clock) == 0 {
+		config.Clock = DefaultClock
+	}
+	if len(config.Source) == 0 {
+		config.Source = "qiscus"
+	}
+	if len(config.Channel) == 0 {
+		config.Channel = "simulator"
+	}
+	if len(config.CustomerName) == 0 {
+		config.CustomerName = "Customer"
+	}
+	if len(config.CustomerEmail) == 0 {
+		config.CustomerEmail = "customer@example.com"
+	}
+
+	s, err := New(layer, config)
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result
+	}
+
+	for i, step := range test.Steps {
+		var events []memory.Event
+		switch {
+		case len(step.Wait) > 0:
+			duration, parseErr := time.ParseDuration(step.Wait)
+			if parseErr != nil {
+				err = parseErr
+				break
+			}
+			events = s.Advance(duration)
+		case len(step.Postback) > 0:
+			events, err = s.Send(step.Postback, map[string]interface{}{"option_id": step.Postback})
+		default:
+			events, err = s.Send(step.Send, nil)
+		}
+
+		prefix := fmt.Sprintf("step %d (%s)", i+1, describe(step))
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %s", prefix, err.Error()))
+			err = nil
+			continue
+		}
+
+		session, exist := s.Session()
+		for _, failure := range compare(step.Expect, events, session, exist) {
+			result.Failures = append(result.Failures, prefix+": "+failure)
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+
+	return result
+}
+
+func describe(step viewmodel.FlowTestStep) string {
+	switch {
+	case len(step.Wait) > 0:
+		return "wait " + step.Wait
+	case len(step.Postback) > 0:
+		return fmt.Sprintf("postback %q", step.Postback)
+	}
+
+	return fmt.Sprintf("send %q", step.Send)
+}
+
+func compare(expected viewmodel.FlowTestExpected, events []memory.Event, session viewmodel.BotSession, sessionExist bool) []string {
+	var failures []string
+
+	var messages, tags []string
+	info := make(map[string]string)
+	handover, resolve := false, false
+	division := ""
+	for _, event := range events {
+		switch event.Kind {
+		case memory.EventMessage, memory.EventPayload:
+			if len(event.Text) > 0 {
+				messages = append(messages, strings.TrimSpace(event.Text))
+			}
+		case memory.EventTag:
+			tags = append(tags, event.Text)
+		case memory.EventUserInfo:
+			info[event.Type] = event.Text
+		case memory.EventHandover:
+			handover = true
+			division = event.Text
+		case memory.EventResolve:
+			resolve = true
+		}
+	}
+
+	if expected.Messages != nil {
+		var want []string
+		for _, message := range expected.Messages {
+			want = append(want, strings.TrimSpace(message))
+		}
+		if !reflect.DeepEqual(want, messages) && !(len(want) == 0 && len(messages) == 0) {
+			failures = append(failures, "messages differ\n"+diff(want, messages))
+		}
+	}
+
+	for _, fragment := range expected.Contains {
+		if !strings.Contains(strings.Join(messages, "\n"), fragment) {
+			failures = append(failures, fmt.Sprintf("no message contains %q", fragment))
+		}
+	}
+
+	if expected.Handover != nil && *expected.Handover != handover {
+		failures = append(failures, fmt.Sprintf("handover: expected %t, got %t", *expected.Handover, handover))
+	}
+	if len(expected.Division) > 0 && (!handover || expected.Division != division) {
+		failures = append(failures, fmt.Sprintf("handover division: expected %q, got %q", expected.Division, division))
+	}
+
+	if expected.Resolve != nil && *expected.Resolve != resolve {
+		failures = append(failures, fmt.Sprintf("resolve: expected %t, got %t", *expected.Resolve, resolve))
+	}
+
+	if expected.Tags != nil {
+		want := append([]string(nil), expected.Tags...)
+		got := append([]string(nil), tags...)
+		sort.Strings(want)
+		sort.Strings(got)
+		if strings.Join(want, ",") != strings.Join(got, ",") {
+			failures = append(failures, fmt.Sprintf("tags: expected [%s], got [%s]", strings.Join(want, ", "), strings.Join(got, ", ")))
+		}
+	}
+
+	for _, key := range sortedKeys(expected.Info) {
+		if value, exist := info[key]; !exist || value != expected.Info[key] {
+			failures = append(failures, fmt.Sprintf("additional info %q: expected %q, got %q", key, expected.Info[key], value))
+		}
+	}
+
+	if len(expected.State) > 0 {
+		state := "none"
+		if sessionExist {
+			state = session.Layer
+		}
+		if state != expected.State {
+			failures = append(failures, fmt.Sprintf("state: expected %q, got %q", expected.State, state))
+		}
+	}
+
+	for _, name := range sortedKeys(expected.Vars) {
+		if value, exist := session.Variables[name]; !exist || value != expected.Vars[name] {
+			failures = append(failures, fmt.Sprintf("variable %q: expected %q, got %q", name, expected.Vars[name], value))
+		}
+	}
+
+	return failures
+}
+
+func diff(want []string, got []string) string {
+	var lines []string
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			lines = append(lines, PrefixLines("- ", want[i]))
+		case i >= len(want):
+			lines = append(lines, PrefixLines("+ ", got[i]))
+		case want[i] != got[i]:
+			lines = append(lines, PrefixLines("- ", want[i]), PrefixLines("+ ", got[i]))
+		default:
+			lines = append(lines, PrefixLines("  ", got[i]))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// PrefixLines prefixes every line of a multi-line failure so it stays aligned
+// under the step that reported it.
+func PrefixLines(prefix string, text string) string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(values map[string]string) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
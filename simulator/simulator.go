@@ -0,0 +1,225 @@
+package simulator
+
+import (
+	"bot-routing-engine/controllers/services"
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/repositories"
+	"bot-routing-engine/repositories/memory"
+	"bot-routing-engine/utils/flow"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	RoomID     = "simulator"
+	TimeLayout = "2006-01-02 15:04"
+)
+
+type Config struct {
+	Clock         string
+	Source        string
+	Channel       string
+	ChannelID     int
+	CustomerName  string
+	CustomerEmail string
+	OfficeHours   string
+	Actions       repositories.ActionRepository
+}
+
+type Simulator struct {
+	config    Config
+	now       time.Time
+	recorder  *memory.Recorder
+	sessions  repositories.SessionStore
+	message   services.MessageService
+	room      services.RoomService
+	scheduler services.SchedulerService
+}
+
+type staticLayerService struct {
+	services.LayerService
+	layer viewmodel.Layer
+}
+
+func (s staticLayerService) GetLayer(source int) (viewmodel.Layer, error) {
+	return s.layer, nil
+}
+
+func New(layer viewmodel.Layer, config Config) (*Simulator, error) {
+	officeHour, err := officeHours(config.OfficeHours)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := config.Actions
+	if actions == nil {
+		actions = repositories.NewActionRepository(nil)
+	}
+
+	recorder := memory.NewRecorder()
+	layerService := staticLayerService{
+		LayerService: services.NewLayerService(nil, nil, actions),
+		layer:        layer,
+	}
+	mulchanRepo := memory.NewMultichannelRepository(recorder, officeHour, divisions(layer))
+	roomRepo := memory.NewRoomRepository(recorder, mulchanRepo.Division)
+	sessionStore := memory.NewSessionStore()
+	roomService := services.NewRoomService(mulchanRepo, roomRepo, sessionStore, repositories.NewLocalLockRepository(), log.New(os.Stderr, "", 0))
+	schedulerService := services.NewSchedulerService(memory.NewTimerRepository(), roomService, log.New(os.Stderr, "", 0))
+	messageService := services.NewMessageService(mulchanRepo, layerService, *roomService, schedulerService)
+
+	s := &Simulator{
+		config:    config,
+		recorder:  recorder,
+		sessions:  sessionStore,
+		message:   messageService,
+		room:      roomService,
+		scheduler: schedulerService,
+	}
+	if err = s.SetClock(config.Clock); err != nil {
+		return nil, err
+	}
+
+	clock := func() time.Time { return s.now }
+	messageService.SetClock(clock)
+	schedulerService.SetClock(clock)
+
+	return s, nil
+}
+
+func officeHours(hours string) (viewmodel.OfficeHourResp, error) {
+	var officeHour viewmodel.OfficeHourResp
+	if hours == "closed" {
+		return officeHour, nil
+	}
+
+	if len(hours) == 0 {
+		hours = "00:00-23:59"
+	}
+
+	times := strings.SplitN(hours, "-", 2)
+	if len(times) != 2 {
+		return officeHour, fmt.Errorf("office hours must look like 08:00-17:00, got %q", hours)
+	}
+	for _, value := range times {
+		if _, err := time.Parse("15:04", value); err != nil {
+			return officeHour, fmt.Errorf("invalid office hour %q", value)
+		}
+	}
+
+	for day := 1; day <= 7; day++ {
+		officeHour.Data.OfficeHours = append(officeHour.Data.OfficeHours, viewmodel.OfficeHour{
+			Day:       day,
+			Starttime: times[0],
+			Endtime:   times[1],
+		})
+	}
+
+	return officeHour, nil
+}
+
+func divisions(layer viewmodel.Layer) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if len(name) > 0 {
+			seen[name] = true
+		}
+	}
+
+	add(os.Getenv("POOL_AGENT_DIVISION"))
+	for _, command := range layer.Commands {
+		add(command.Division)
+	}
+	flow.Walk(layer, func(node viewmodel.Layer) {
+		add(node.Division)
+		if node.InvalidLimit != nil {
+			add(node.InvalidLimit.Division)
+		}
+	})
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *Simulator) Now() time.Time {
+	return s.now
+}
+
+func (s *Simulator) SetClock(value string) error {
+	loc, _ := time.LoadLocation(os.Getenv("TIMEZONE"))
+	if len(value) == 0 {
+		s.now = time.Now().In(loc)
+		return nil
+	}
+
+	now, err := time.ParseInLocation(TimeLayout, value, loc)
+	if err != nil {
+		return fmt.Errorf("clock must look like %q, got %q", TimeLayout, value)
+	}
+	s.now = now
+
+	return nil
+}
+
+func (s *Simulator) Send(text string, payload interface{}) ([]memory.Event, error) {
+	roomOptions, err := json.Marshal(viewmodel.Option{
+		Channel: s.config.Channel,
+		Source:  s.config.Source,
+		ChannelDetails: struct {
+			ChannelID int `json:"channel_id"`
+		}{s.config.ChannelID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	input := new(viewmodel.WebhookRequest)
+	input.Payload.Type = "text"
+	input.Payload.From.Name = s.config.CustomerName
+	input.Payload.From.Email = s.config.CustomerEmail
+	input.Payload.Room.ID = RoomID
+	input.Payload.Room.Name = s.config.CustomerName
+	input.Payload.Room.Options = string(roomOptions)
+	input.Payload.Message.Text = text
+	input.Payload.Message.Type = "text"
+	input.Payload.Message.Payload = payload
+	if payload != nil {
+		input.Payload.Message.Type = "postback"
+	}
+
+	drafts, err := s.message.Determine(input)
+	if err != nil {
+		return s.recorder.Drain(), err
+	}
+
+	err = s.room.Dispatch(drafts)
+
+	return s.recorder.Drain(), err
+}
+
+func (s *Simulator) Advance(duration time.Duration) []memory.Event {
+	s.now = s.now.Add(duration)
+	s.scheduler.Tick(s.now)
+
+	return s.recorder.Drain()
+}
+
+func (s *Simulator) Session() (viewmodel.BotSession, bool) {
+	session, exist, _ := s.sessions.Load(RoomID)
+
+	return session, exist
+}
+
+func (s *Simulator) Reset() error {
+	return s.sessions.Delete(RoomID)
+}
@@ -0,0 +1,98 @@
+package condition
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func Match(rules []viewmodel.Rule, lookup func(field string) (string, bool)) bool {
+	for _, rule := range rules {
+		value, exist := lookup(rule.Field)
+		if !evaluate(rule, value, exist) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func evaluate(rule viewmodel.Rule, value string, exist bool) bool {
+	switch rule.Operator {
+	case "exists":
+		return exist && len(value) > 0
+	case "not_exists":
+		return !exist || len(value) == 0
+	case "", "eq":
+		return strings.EqualFold(value, rule.Value)
+	case "neq":
+		return !strings.EqualFold(value, rule.Value)
+	case "in":
+		return contains(rule.Values, value)
+	case "not_in":
+		return !contains(rule.Values, value)
+	case "contains":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(rule.Value))
+	case "prefix":
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(rule.Value))
+	case "suffix":
+		return strings.HasSuffix(strings.ToLower(value), strings.ToLower(rule.Value))
+	case "regex":
+		matched, err := regexp.MatchString(rule.Value, value)
+		return err == nil && matched
+	case "gt", "gte", "lt", "lte":
+		left, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		right, err := strconv.ParseFloat(rule.Value, 64)
+		if err != nil {
+			return false
+		}
+		switch rule.Operator {
+		case "gt":
+			return left > right
+		case "gte":
+			return left >= right
+		case "lt":
+			return left < right
+		}
+		return left <= right
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Check(rule viewmodel.Rule) error {
+	if len(rule.Field) == 0 {
+		return fmt.Errorf("rule field must not be empty")
+	}
+
+	switch rule.Operator {
+	case "", "eq", "neq", "contains", "prefix", "suffix", "exists", "not_exists", "in", "not_in":
+	case "regex":
+		if _, err := regexp.Compile(rule.Value); err != nil {
+			return fmt.Errorf("invalid rule pattern: %s", err.Error())
+		}
+	case "gt", "gte", "lt", "lte":
+		if _, err := strconv.ParseFloat(rule.Value, 64); err != nil {
+			return fmt.Errorf("rule value %q is not a number", rule.Value)
+		}
+	default:
+		return fmt.Errorf("unknown rule operator %q", rule.Operator)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package flow
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"fmt"
+	"sort"
+)
+
+const RootID = "root"
+
+func AssignIDs(layer *viewmodel.Layer) {
+	if len(layer.ID) == 0 {
+		layer.ID = RootID
+	}
+	assignChildIDs("", layer)
+
+	for _, name := range SubflowNames(*layer) {
+		subflow := layer.Subflows[name]
+		if len(subflow.ID) == 0 {
+			subflow.ID = name
+		}
+		assignChildIDs(name+".", &subflow)
+		layer.Subflows[name] = subflow
+	}
+}
+
+// Options without an explicit id fall back to their index path, the same
+// path legacy index states are migrated to.
+func assignChildIDs(prefix string, layer *viewmodel.Layer) {
+	for i := range layer.Options {
+		path := fmt.Sprintf("%s%d", prefix, i+1)
+		if len(layer.Options[i].ID) == 0 {
+			layer.Options[i].ID = path
+		}
+		assignChildIDs(path+".", &layer.Options[i])
+	}
+}
+
+func SubflowNames(layer viewmodel.Layer) []string {
+	var names []string
+	for name := range layer.Subflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func trees(layer viewmodel.Layer) []viewmodel.Layer {
+	layers := []viewmodel.Layer{layer}
+	for _, name := range SubflowNames(layer) {
+		layers = append(layers, layer.Subflows[name])
+	}
+
+	return layers
+}
+
+func Find(layer viewmodel.Layer, ID string) (viewmodel.Layer, bool) {
+	for _, tree := range trees(layer) {
+		if found, ok := find(tree, ID); ok {
+			return found, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
+}
+
+func find(layer viewmodel.Layer, ID string) (viewmodel.Layer, bool) {
+	if layer.ID == ID {
+		return layer, true
+	}
+
+	for _, option := range layer.Options {
+		if found, ok := find(option, ID); ok {
+			return found, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
+}
+
+func Parent(layer viewmodel.Layer, ID string) (viewmodel.Layer, bool) {
+	for _, tree := range trees(layer) {
+		if parent, ok := parentOf(tree, ID); ok {
+			return parent, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
+}
+
+func parentOf(layer viewmodel.Layer, ID string) (viewmodel.Layer, bool) {
+	for _, option := range layer.Options {
+		if option.ID == ID {
+			return layer, true
+		}
+
+		if parent, ok := parentOf(option, ID); ok {
+			return parent, true
+		}
+	}
+
+	return viewmodel.Layer{}, false
+}
+
+func FromPath(layer viewmodel.Layer, states []int) (viewmodel.Layer, bool) {
+	for _, state := range states {
+		if state <= 0 || state > len(layer.Options) {
+			return viewmodel.Layer{}, false
+		}
+		layer = layer.Options[state-1]
+	}
+
+	return layer, true
+}
+
+func Index(layer viewmodel.Layer) map[string]viewmodel.Layer {
+	nodes := make(map[string]viewmodel.Layer)
+	Walk(layer, func(node viewmodel.Layer) {
+		nodes[node.ID] = node
+	})
+
+	return nodes
+}
+
+func IDs(layer viewmodel.Layer) []string {
+	var IDs []string
+	Walk(layer, func(node viewmodel.Layer) {
+		IDs = append(IDs, node.ID)
+	})
+
+	return IDs
+}
+
+func Walk(layer viewmodel.Layer, visit func(node viewmodel.Layer)) {
+	for _, tree := range trees(layer) {
+		walk(tree, visit)
+	}
+}
+
+func walk(layer viewmodel.Layer, visit func(node viewmodel.Layer)) {
+	visit(layer)
+	for _, option := range layer.Options {
+		walk(option, visit)
+	}
+}
@@ -0,0 +1,322 @@
+package graph
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/utils/flow"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+
+	excerptLength = 40
+)
+
+var mermaidIDPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+type node struct {
+	ID    string
+	Kind  string
+	Label string
+}
+
+type edge struct {
+	From   string
+	To     string
+	Label  string
+	Dashed bool
+}
+
+func Render(layer viewmodel.Layer, format string, locale string) (string, error) {
+	switch format {
+	case "", FormatDOT:
+		return DOT(layer, locale), nil
+	case FormatMermaid:
+		return Mermaid(layer, locale), nil
+	}
+
+	return "", fmt.Errorf("unknown graph format %q", format)
+}
+
+func DOT(layer viewmodel.Layer, locale string) string {
+	nodes, edges := collect(layer, locale)
+
+	var builder strings.Builder
+	builder.WriteString("digraph layer {\n")
+	builder.WriteString("\trankdir=LR;\n")
+	builder.WriteString("\tnode [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "\t%s [label=%s%s];\n", dotQuote(node.ID), dotQuote(node.Label), dotStyle(node.Kind))
+	}
+	for _, edge := range edges {
+		var attributes []string
+		if len(edge.Label) > 0 {
+			attributes = append(attributes, "label="+dotQuote(edge.Label))
+		}
+		if edge.Dashed {
+			attributes = append(attributes, "style=dashed")
+		}
+
+		fmt.Fprintf(&builder, "\t%s -> %s", dotQuote(edge.From), dotQuote(edge.To))
+		if len(attributes) > 0 {
+			fmt.Fprintf(&builder, " [%s]", strings.Join(attributes, ", "))
+		}
+		builder.WriteString(";\n")
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func dotStyle(kind string) string {
+	switch kind {
+	case "handover":
+		return ", shape=box, style=\"filled\", fillcolor=\"#ffd8a8\""
+	case "resolve":
+		return ", shape=doubleoctagon, style=\"filled\", fillcolor=\"#b2f2bb\""
+	case "input":
+		return ", shape=parallelogram, style=\"filled\", fillcolor=\"#a5d8ff\""
+	case viewmodel.LayerTypeCondition:
+		return ", shape=diamond"
+	case viewmodel.LayerTypeGoto:
+		return ", shape=cds"
+	case viewmodel.LayerTypeHTTP:
+		return ", shape=hexagon"
+	case viewmodel.LayerTypeDynamic:
+		return ", shape=folder"
+	}
+
+	return ""
+}
+
+func dotQuote(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "\"", "\\\"")
+	text = strings.ReplaceAll(text, "\n", "\\n")
+
+	return "\"" + text + "\""
+}
+
+func Mermaid(layer viewmodel.Layer, locale string) string {
+	nodes, edges := collect(layer, locale)
+
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "\t%s%s\n", mermaidID(node.ID), mermaidShape(node.Kind, mermaidQuote(node.Label)))
+	}
+	for _, edge := range edges {
+		arrow := "-->"
+		if edge.Dashed {
+			arrow = "-.->"
+		}
+		if len(edge.Label) > 0 {
+			arrow += "|" + mermaidQuote(edge.Label) + "|"
+		}
+
+		fmt.Fprintf(&builder, "\t%s %s %s\n", mermaidID(edge.From), arrow, mermaidID(edge.To))
+	}
+
+	builder.WriteString("\tclassDef handover fill:#ffd8a8,stroke:#e8590c\n")
+	builder.WriteString("\tclassDef resolve fill:#b2f2bb,stroke:#2f9e44\n")
+	builder.WriteString("\tclassDef input fill:#a5d8ff,stroke:#1971c2\n")
+	for _, node := range nodes {
+		switch node.Kind {
+		case "handover", "resolve", "input":
+			fmt.Fprintf(&builder, "\tclass %s %s\n", mermaidID(node.ID), node.Kind)
+		}
+	}
+
+	return builder.String()
+}
+
+func mermaidShape(kind string, label string) string {
+	switch kind {
+	case "handover":
+		return "[[" + label + "]]"
+	case "resolve":
+		return "([" + label + "])"
+	case "input":
+		return "[/" + label + "/]"
+	case viewmodel.LayerTypeCondition:
+		return "{" + label + "}"
+	case viewmodel.LayerTypeHTTP:
+		return "{{" + label + "}}"
+	case viewmodel.LayerTypeGoto:
+		return ">" + label + "]"
+	case viewmodel.LayerTypeDynamic:
+		return "[(" + label + ")]"
+	}
+
+	return "[" + label + "]"
+}
+
+func mermaidID(ID string) string {
+	return "n_" + mermaidIDPattern.ReplaceAllString(ID, "_")
+}
+
+func mermaidQuote(text string) string {
+	text = strings.ReplaceAll(text, "\"", "#quot;")
+	text = strings.ReplaceAll(text, "\n", "<br/>")
+
+	return "\"" + text + "\""
+}
+
+func collect(layer viewmodel.Layer, locale string) ([]node, []edge) {
+	var nodes []node
+	var edges []edge
+
+	flow.Walk(layer, func(current viewmodel.Layer) {
+		nodes = append(nodes, node{
+			ID:    current.ID,
+			Kind:  kind(current),
+			Label: label(current, locale),
+		})
+
+		for i, option := range current.Options {
+			edges = append(edges, edge{
+				From:  current.ID,
+				To:    option.ID,
+				Label: edgeLabel(current, option, i, locale),
+			})
+		}
+
+		if current.Type == viewmodel.LayerTypeGoto {
+			if target, exist := gotoTarget(layer, current.Goto); exist {
+				edges = append(edges, edge{From: current.ID, To: target, Label: "goto", Dashed: true})
+			}
+		}
+
+		if current.Type == viewmodel.LayerTypeDynamic && current.Source != nil {
+			if target, exist := gotoTarget(layer, current.Source.Empty); exist {
+				edges = append(edges, edge{From: current.ID, To: target, Label: "empty", Dashed: true})
+			}
+		}
+
+		if current.InvalidLimit != nil && current.InvalidLimit.Action == viewmodel.CommandGoto {
+			if target, exist := gotoTarget(layer, current.InvalidLimit.Goto); exist {
+				edges = append(edges, edge{From: current.ID, To: target, Label: "invalid", Dashed: true})
+			}
+		}
+	})
+
+	return nodes, edges
+}
+
+func gotoTarget(layer viewmodel.Layer, target string) (string, bool) {
+	if target == flow.RootID {
+		return layer.ID, true
+	}
+
+	if subflow, exist := layer.Subflows[target]; exist {
+		return subflow.ID, true
+	}
+
+	if found, exist := flow.Find(layer, target); exist {
+		return found.ID, true
+	}
+
+	return "", false
+}
+
+func kind(layer viewmodel.Layer) string {
+	switch {
+	case layer.Handover:
+		return "handover"
+	case layer.Resolve:
+		return "resolve"
+	case layer.Input:
+		return "input"
+	}
+
+	return layer.Type
+}
+
+func label(layer viewmodel.Layer, locale string) string {
+	lines := []string{layer.ID}
+
+	switch {
+	case layer.Handover:
+		handover := "Handover"
+		if len(layer.Division) > 0 {
+			handover += " (" + layer.Division + ")"
+		}
+		lines = append(lines, handover)
+	case layer.Resolve:
+		lines = append(lines, "Resolve")
+	case layer.Input:
+		input := "Input"
+		if len(layer.Variable) > 0 {
+			input += " → " + layer.Variable
+		}
+		lines = append(lines, input)
+	case layer.Type == viewmodel.LayerTypeGoto:
+		lines = append(lines, "Goto "+layer.Goto)
+	case layer.Type == viewmodel.LayerTypeHTTP && layer.Request != nil:
+		method := layer.Request.Method
+		if len(method) == 0 {
+			method = "GET"
+		}
+		lines = append(lines, strings.ToUpper(method)+" "+excerpt(layer.Request.URL))
+	case layer.Type == viewmodel.LayerTypeDynamic:
+		lines = append(lines, "Dynamic options")
+	case len(layer.Type) > 0:
+		lines = append(lines, strings.Title(layer.Type))
+	}
+
+	if message := excerpt(layer.Message.Get(locale, "")); len(message) > 0 {
+		lines = append(lines, message)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func edgeLabel(parent viewmodel.Layer, option viewmodel.Layer, index int, locale string) string {
+	switch {
+	case parent.Type == viewmodel.LayerTypeCondition || parent.Type == viewmodel.LayerTypeHTTP:
+		if len(option.When) == 0 {
+			return "else"
+		}
+		var rules []string
+		for _, rule := range option.When {
+			value := rule.Value
+			if len(rule.Values) > 0 {
+				value = strings.Join(rule.Values, ",")
+			}
+			operator := rule.Operator
+			if len(operator) == 0 {
+				operator = "eq"
+			}
+			rules = append(rules, strings.TrimSpace(fmt.Sprintf("%s %s %s", rule.Field, operator, value)))
+		}
+		return excerpt(strings.Join(rules, " and "))
+	case parent.Input:
+		return ""
+	case parent.Type == viewmodel.LayerTypeDynamic:
+		return "selected"
+	}
+
+	label := fmt.Sprintf("%d", index+1)
+	if title := excerpt(option.Title.Get(locale, "")); len(title) > 0 {
+		label += ". " + title
+	}
+
+	return label
+}
+
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) > excerptLength {
+		return string(runes[:excerptLength]) + "…"
+	}
+
+	return text
+}
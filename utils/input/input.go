@@ -0,0 +1,76 @@
+package input
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultDateFormat = "02-01-2006"
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+func Validate(value string, validation *viewmodel.InputValidation) bool {
+	value = strings.TrimSpace(value)
+	if validation == nil {
+		return len(value) > 0
+	}
+
+	switch validation.Type {
+	case "regex":
+		matched, err := regexp.MatchString(validation.Pattern, value)
+		return err == nil && matched
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "phone":
+		return phonePattern.MatchString(strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(value))
+	case "number":
+		number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+		if err != nil {
+			return false
+		}
+		if validation.Min != nil && number < *validation.Min {
+			return false
+		}
+		if validation.Max != nil && number > *validation.Max {
+			return false
+		}
+		return true
+	case "date":
+		format := validation.Format
+		if len(format) == 0 {
+			format = DefaultDateFormat
+		}
+		_, err := time.Parse(format, value)
+		return err == nil
+	}
+
+	return len(value) > 0
+}
+
+func CheckValidation(validation *viewmodel.InputValidation) error {
+	if validation == nil {
+		return nil
+	}
+
+	switch validation.Type {
+	case "regex":
+		if _, err := regexp.Compile(validation.Pattern); err != nil {
+			return fmt.Errorf("invalid validation pattern: %s", err.Error())
+		}
+	case "email", "phone", "number", "date":
+	default:
+		return fmt.Errorf("unknown validation type %q", validation.Type)
+	}
+
+	if validation.Min != nil && validation.Max != nil && *validation.Min > *validation.Max {
+		return fmt.Errorf("validation min is greater than max")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package input
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var numberWords = map[string]int{
+	"nol": 0, "zero": 0,
+	"satu": 1, "one": 1, "pertama": 1, "first": 1,
+	"dua": 2, "two": 2, "kedua": 2, "second": 2,
+	"tiga": 3, "three": 3, "ketiga": 3, "third": 3,
+	"empat": 4, "four": 4, "keempat": 4, "fourth": 4,
+	"lima": 5, "five": 5, "kelima": 5, "fifth": 5,
+	"enam": 6, "six": 6, "keenam": 6, "sixth": 6,
+	"tujuh": 7, "seven": 7, "ketujuh": 7, "seventh": 7,
+	"delapan": 8, "eight": 8, "kedelapan": 8, "eighth": 8,
+	"sembilan": 9, "nine": 9, "kesembilan": 9, "ninth": 9,
+	"sepuluh": 10, "ten": 10, "kesepuluh": 10, "tenth": 10,
+}
+
+var keycapReplacer = strings.NewReplacer("\ufe0f", "", "\u20e3", "", "\U0001f51f", "10")
+
+func Normalize(text string) string {
+	text = keycapReplacer.Replace(text)
+
+	var builder strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+func Number(text string) (int, bool) {
+	normalized := strings.TrimRight(Normalize(text), ".)")
+
+	if number, err := strconv.Atoi(normalized); err == nil {
+		return number, true
+	}
+
+	number, exist := numberWords[normalized]
+	return number, exist
+}
+
+func MatchKeyword(text string, keywords ...string) bool {
+	normalized := Normalize(text)
+	if len(normalized) == 0 {
+		return false
+	}
+
+	for _, keyword := range keywords {
+		if Normalize(keyword) == normalized {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package input
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Halo", "halo"},
+		{"  Cek   Pesanan ", "cek pesanan"},
+		{"Café", "cafe"},
+		{"PÉNDAFTARAN", "pendaftaran"},
+		{"1️⃣", "1"},
+		{"1⃣", "1"},
+		{"🔟", "10"},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if got := Normalize(c.text); got != c.want {
+			t.Errorf("Normalize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	cases := []struct {
+		text  string
+		want  int
+		found bool
+	}{
+		{"1", 1, true},
+		{" 2 ", 2, true},
+		{"1.", 1, true},
+		{"1)", 1, true},
+		{"3️⃣", 3, true},
+		{"🔟", 10, true},
+		{"satu", 1, true},
+		{"One", 1, true},
+		{"Kedua", 2, true},
+		{"sepuluh", 10, true},
+		// Out of range numbers are parsed as is, callers check them against
+		// the options on offer.
+		{"0", 0, true},
+		{"99", 99, true},
+		{"-1", -1, true},
+		{"sebelas", 0, false},
+		{"1a", 0, false},
+		{"", 0, false},
+	}
+
+	for _, c := range cases {
+		got, found := Number(c.text)
+		if got != c.want || found != c.found {
+			t.Errorf("Number(%q) = %d, %t, want %d, %t", c.text, got, found, c.want, c.found)
+		}
+	}
+}
+
+func TestMatchKeyword(t *testing.T) {
+	cases := []struct {
+		text     string
+		keywords []string
+		want     bool
+	}{
+		{"cek pesanan", []string{"Cek Pesanan"}, true},
+		{"  CEK   pesanan", []string{"cek pesanan"}, true},
+		{"menu", []string{"bantuan", "Menu"}, true},
+		{"resume", []string{"Résumé"}, true},
+		{"cek", []string{"cek pesanan"}, false},
+		{"", []string{""}, false},
+		{"menu", nil, false},
+	}
+
+	for _, c := range cases {
+		if got := MatchKeyword(c.text, c.keywords...); got != c.want {
+			t.Errorf("MatchKeyword(%q, %q) = %t, want %t", c.text, c.keywords, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+func Lookup(data interface{}, path string) (string, bool) {
+	value, exist := Find(data, path)
+	if !exist {
+		return "", false
+	}
+
+	return stringify(value)
+}
+
+func Find(data interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+	current := data
+	if len(path) > 0 {
+		for _, key := range strings.Split(strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", ""), ".") {
+			switch typed := current.(type) {
+			case map[string]interface{}:
+				value, exist := typed[key]
+				if !exist {
+					return nil, false
+				}
+				current = value
+			case []interface{}:
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 || index >= len(typed) {
+					return nil, false
+				}
+				current = typed[index]
+			default:
+				return nil, false
+			}
+		}
+	}
+
+	return current, true
+}
+
+func Flatten(data interface{}, prefix string) map[string]string {
+	values := make(map[string]string)
+	flatten(data, prefix, values)
+
+	return values
+}
+
+func flatten(data interface{}, prefix string, values map[string]string) {
+	if value, exist := stringify(data); exist {
+		values[prefix] = value
+	}
+
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			flatten(value, prefix+"."+key, values)
+		}
+	case []interface{}:
+		for i, value := range typed {
+			flatten(value, prefix+"."+strconv.Itoa(i), values)
+		}
+	}
+}
+
+func stringify(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return typed, true
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(typed), true
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+
+	return string(encoded), true
+}
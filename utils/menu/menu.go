@@ -0,0 +1,147 @@
+package menu
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	StyleNumber      = "number"
+	StyleEmoji       = "emoji"
+	StyleBracket     = "bracket"
+	StyleParenthesis = "parenthesis"
+)
+
+var keycaps = []string{"0️⃣", "1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣"}
+
+var defaultMoreLabel = viewmodel.Text{"id": "Lainnya", "en": "More"}
+
+func Enabled(layer viewmodel.Layer, format *viewmodel.MenuFormat) bool {
+	if !layer.Menu && (format == nil || !format.Auto) {
+		return false
+	}
+
+	if len(layer.Options) == 0 || layer.Input {
+		return false
+	}
+
+	for _, option := range layer.Options {
+		if option.Title.IsEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func PageSize(format *viewmodel.MenuFormat) int {
+	if format == nil {
+		return 0
+	}
+
+	return format.PageSize
+}
+
+func Paginated(layer viewmodel.Layer, format *viewmodel.MenuFormat) bool {
+	size := PageSize(format)
+	return size > 0 && len(layer.Options) > size
+}
+
+func Pages(layer viewmodel.Layer, format *viewmodel.MenuFormat) int {
+	if !Paginated(layer, format) {
+		return 1
+	}
+
+	size := PageSize(format)
+	return (len(layer.Options) + size - 1) / size
+}
+
+func Render(layer viewmodel.Layer, format *viewmodel.MenuFormat, page int, locale string, fallback string) string {
+	if format == nil {
+		format = &viewmodel.MenuFormat{}
+	}
+
+	options := layer.Options
+	paginated := Paginated(layer, format)
+	if paginated {
+		size := PageSize(format)
+		start := page * size
+		end := start + size
+		if end > len(options) {
+			end = len(options)
+		}
+		options = options[start:end]
+	}
+
+	var lines []string
+	if header := format.Header.Get(locale, fallback); len(header) > 0 {
+		lines = append(lines, header)
+	}
+
+	for i, option := range options {
+		lines = append(lines, Item(i+1, option.Title.Get(locale, fallback), format))
+	}
+
+	if paginated {
+		moreLabel := format.MoreLabel
+		if moreLabel.IsEmpty() {
+			moreLabel = defaultMoreLabel
+		}
+		lines = append(lines, Item(len(options)+1, moreLabel.Get(locale, fallback), format))
+	}
+
+	if footer := format.Footer.Get(locale, fallback); len(footer) > 0 {
+		lines = append(lines, footer)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func Item(number int, title string, format *viewmodel.MenuFormat) string {
+	separator := format.Separator
+
+	var label string
+	switch format.Style {
+	case StyleEmoji:
+		var builder strings.Builder
+		for _, digit := range strconv.Itoa(number) {
+			builder.WriteString(keycaps[digit-'0'])
+		}
+		label = builder.String()
+	case StyleBracket:
+		label = fmt.Sprintf("[%d]", number)
+	case StyleParenthesis:
+		label = fmt.Sprintf("%d)", number)
+	default:
+		label = strconv.Itoa(number)
+		if len(separator) == 0 {
+			separator = ". "
+		}
+	}
+
+	if len(separator) == 0 {
+		separator = " "
+	}
+
+	return label + separator + title
+}
+
+func CheckFormat(format *viewmodel.MenuFormat) error {
+	if format == nil {
+		return nil
+	}
+
+	switch format.Style {
+	case "", StyleNumber, StyleEmoji, StyleBracket, StyleParenthesis:
+	default:
+		return fmt.Errorf("unknown menu style %q", format.Style)
+	}
+
+	if format.PageSize < 0 {
+		return fmt.Errorf("menu page_size must not be negative")
+	}
+
+	return nil
+}
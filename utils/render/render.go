@@ -0,0 +1,136 @@
+package render
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var placeholderPattern = regexp.MustCompile(`{{\s*(.*?)\s*}}`)
+
+func Render(text string, values map[string]string, now time.Time) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		expression := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		return evaluate(expression, values, now)
+	})
+}
+
+// JSON renders a JSON template such as an http request body. Every value is
+// escaped as JSON string content, so customer input can't break the document.
+func JSON(text string, values map[string]string, now time.Time) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		expression := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		pipes := split(expression, '|')
+		last := split(strings.TrimSpace(pipes[len(pipes)-1]), ' ')
+		if len(pipes) == 1 || len(last) == 0 || last[0] != "json" {
+			expression += " | json"
+		}
+		return evaluate(expression, values, now)
+	})
+}
+
+func evaluate(expression string, values map[string]string, now time.Time) string {
+	pipes := split(expression, '|')
+
+	name := strings.TrimSpace(pipes[0])
+	value, exist := values[name]
+	isNow := name == "now"
+	if isNow {
+		value, exist = now.Format("2006-01-02 15:04"), true
+	}
+
+	for _, pipe := range pipes[1:] {
+		args := split(strings.TrimSpace(pipe), ' ')
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "default":
+			if (!exist || len(value) == 0) && len(args) > 1 {
+				value, exist = args[1], true
+			}
+		case "date":
+			if len(args) < 2 {
+				continue
+			}
+			if isNow {
+				value = now.Format(args[1])
+			} else if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+				value = parsed.In(now.Location()).Format(args[1])
+			}
+		case "upper":
+			value = strings.ToUpper(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "title":
+			value = strings.Title(strings.ToLower(value))
+		case "urlencode":
+			value = url.QueryEscape(value)
+		case "json":
+			escaped, _ := json.Marshal(value)
+			value = string(escaped[1 : len(escaped)-1])
+		}
+	}
+
+	return value
+}
+
+func split(text string, separator rune) []string {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			if separator != ' ' {
+				current.WriteRune(r)
+			}
+		case r == separator && !quoted:
+			if separator != ' ' || current.Len() > 0 {
+				parts = append(parts, current.String())
+			}
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if separator != ' ' || current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+
+	return parts
+}
+
+func Payload(value interface{}, values map[string]string, now time.Time) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return Render(typed, values, now)
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = Payload(item, values, now)
+		}
+	case []map[string]interface{}:
+		for _, item := range typed {
+			Payload(item, values, now)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = Payload(item, values, now)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,279 @@
+package rich
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"bot-routing-engine/utils/menu"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	TypeButtons    = "buttons"
+	TypeQuickReply = "quick_reply"
+	TypeImage      = "image"
+	TypeFile       = "file"
+	TypeCarousel   = "carousel"
+)
+
+var defaultSupport = map[string][]string{
+	"qiscus":   {TypeButtons, TypeQuickReply, TypeImage, TypeFile, TypeCarousel},
+	"line":     {TypeButtons, TypeQuickReply, TypeImage, TypeFile, TypeCarousel},
+	"fb":       {TypeButtons, TypeQuickReply, TypeImage, TypeFile, TypeCarousel},
+	"wa":       {TypeButtons, TypeImage, TypeFile},
+	"telegram": {TypeButtons, TypeImage, TypeFile},
+	"ig":       {TypeImage, TypeFile},
+}
+
+func Supported(source string, richType string) bool {
+	support := defaultSupport
+	if value, exist := os.LookupEnv("RICH_MESSAGE_SUPPORT"); exist {
+		var configured map[string][]string
+		if err := json.Unmarshal([]byte(value), &configured); err == nil {
+			support = configured
+		}
+	}
+
+	for _, supported := range support[strings.ToLower(source)] {
+		if supported == richType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Build(layer viewmodel.Layer, message string, locale string, fallback string) viewmodel.OutboundMessage {
+	richMessage := layer.Rich
+
+	text := richMessage.Text.Get(locale, fallback)
+	if len(text) == 0 {
+		text = message
+	}
+
+	switch richMessage.Type {
+	case TypeButtons:
+		return viewmodel.OutboundMessage{
+			Type: "buttons",
+			Payload: map[string]interface{}{
+				"text":    text,
+				"buttons": buttons(layer.Options, locale, fallback),
+			},
+		}
+	case TypeQuickReply:
+		return viewmodel.OutboundMessage{
+			Type: "custom",
+			Payload: map[string]interface{}{
+				"type": "quick_reply",
+				"content": map[string]interface{}{
+					"text":    text,
+					"buttons": buttons(layer.Options, locale, fallback),
+				},
+			},
+		}
+	case TypeImage, TypeFile:
+		caption := richMessage.Caption.Get(locale, fallback)
+		if len(caption) == 0 {
+			caption = text
+		}
+		return viewmodel.OutboundMessage{
+			Type: "file_attachment",
+			Payload: map[string]interface{}{
+				"url":     richMessage.URL,
+				"caption": caption,
+			},
+		}
+	case TypeCarousel:
+		var cards []map[string]interface{}
+		for _, card := range richMessage.Cards {
+			cards = append(cards, carouselCard(card, layer, locale, fallback))
+		}
+		return viewmodel.OutboundMessage{
+			Type: "carousel",
+			Payload: map[string]interface{}{
+				"cards": cards,
+			},
+		}
+	}
+
+	return viewmodel.OutboundMessage{Type: "text"}
+}
+
+func buttons(options []viewmodel.Layer, locale string, fallback string) []map[string]interface{} {
+	var buttons []map[string]interface{}
+	for i, option := range options {
+		label := option.Title.Get(locale, fallback)
+		if len(label) == 0 {
+			label = fmt.Sprintf("%d", i+1)
+		}
+		buttons = append(buttons, postbackButton(label, option.ID))
+	}
+
+	return buttons
+}
+
+func postbackButton(label string, optionID string) map[string]interface{} {
+	return map[string]interface{}{
+		"label":         label,
+		"type":          "postback",
+		"postback_text": label,
+		"payload": map[string]interface{}{
+			"url":    "",
+			"method": "",
+			"payload": map[string]interface{}{
+				"option_id": optionID,
+			},
+		},
+	}
+}
+
+func carouselCard(card viewmodel.RichCard, layer viewmodel.Layer, locale string, fallback string) map[string]interface{} {
+	title := card.Title.Get(locale, fallback)
+	result := map[string]interface{}{
+		"image":       card.Image,
+		"title":       title,
+		"description": card.Description.Get(locale, fallback),
+		"url":         card.URL,
+	}
+
+	if len(card.Option) > 0 {
+		label := card.ButtonLabel.Get(locale, fallback)
+		if len(label) == 0 {
+			label = title
+		}
+		result["buttons"] = []map[string]interface{}{postbackButton(label, card.Option)}
+	}
+
+	return result
+}
+
+func Fallback(layer viewmodel.Layer, message string, format *viewmodel.MenuFormat, locale string, fallback string) string {
+	richMessage := layer.Rich
+
+	text := richMessage.Text.Get(locale, fallback)
+	if len(text) == 0 {
+		text = message
+	}
+
+	var lines []string
+	if len(text) > 0 {
+		lines = append(lines, text)
+	}
+
+	switch richMessage.Type {
+	case TypeButtons, TypeQuickReply:
+		if len(layer.Options) > 0 && !menu.Enabled(layer, format) {
+			textFormat := viewmodel.MenuFormat{}
+			if format != nil {
+				textFormat = *format
+				textFormat.PageSize = 0
+			}
+			for i, option := range layer.Options {
+				lines = append(lines, menu.Item(i+1, option.Title.Get(locale, fallback), &textFormat))
+			}
+		}
+	case TypeImage, TypeFile:
+		if caption := richMessage.Caption.Get(locale, fallback); len(caption) > 0 && caption != text {
+			lines = append(lines, caption)
+		}
+		lines = append(lines, richMessage.URL)
+	case TypeCarousel:
+		for i, card := range richMessage.Cards {
+			item := card.Title.Get(locale, fallback)
+			if description := card.Description.Get(locale, fallback); len(description) > 0 {
+				item += " - " + description
+			}
+			if len(card.Option) > 0 {
+				if position := optionPosition(layer, card.Option); position > 0 {
+					item = menu.Item(position, item, &viewmodel.MenuFormat{})
+				}
+			} else if len(card.URL) > 0 {
+				item += " " + card.URL
+			} else {
+				item = fmt.Sprintf("%d. %s", i+1, item)
+			}
+			lines = append(lines, item)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func optionPosition(layer viewmodel.Layer, optionID string) int {
+	for i, option := range layer.Options {
+		if option.ID == optionID {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+func Check(layer viewmodel.Layer) error {
+	richMessage := layer.Rich
+	if richMessage == nil {
+		return nil
+	}
+
+	switch richMessage.Type {
+	case TypeButtons, TypeQuickReply:
+		if len(layer.Options) == 0 {
+			return fmt.Errorf("%s message requires options", richMessage.Type)
+		}
+	case TypeImage, TypeFile:
+		if len(richMessage.URL) == 0 {
+			return fmt.Errorf("%s message requires a url", richMessage.Type)
+		}
+	case TypeCarousel:
+		if len(richMessage.Cards) == 0 {
+			return fmt.Errorf("carousel message requires cards")
+		}
+		for _, card := range richMessage.Cards {
+			if len(card.Option) > 0 && optionPosition(layer, card.Option) == 0 {
+				return fmt.Errorf("carousel card option %q is not an option of this node", card.Option)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rich message type %q", richMessage.Type)
+	}
+
+	return nil
+}
+
+func OptionID(payload interface{}) (string, bool) {
+	switch typed := payload.(type) {
+	case map[string]interface{}:
+		if optionID, ok := typed["option_id"].(string); ok && len(optionID) > 0 {
+			return optionID, true
+		}
+
+		for _, key := range []string{"button_reply", "list_reply"} {
+			if reply, ok := typed[key].(map[string]interface{}); ok {
+				if optionID, ok := reply["id"].(string); ok && len(optionID) > 0 {
+					return optionID, true
+				}
+			}
+		}
+
+		if data, ok := typed["data"].(string); ok {
+			if values, err := url.ParseQuery(data); err == nil && len(values.Get("option_id")) > 0 {
+				return values.Get("option_id"), true
+			}
+		}
+
+		for _, key := range []string{"payload", "postback", "interactive"} {
+			if optionID, ok := OptionID(typed[key]); ok {
+				return optionID, true
+			}
+		}
+	case string:
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(typed), &decoded); err == nil {
+			return OptionID(decoded)
+		}
+	}
+
+	return "", false
+}
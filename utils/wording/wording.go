@@ -0,0 +1,61 @@
+package wording
+
+import (
+	"bot-routing-engine/entities/viewmodel"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	InvalidOption    = "invalid_option"
+	InvalidInput     = "invalid_input"
+	NotInWorkingHour = "not_in_working_hour"
+	WaitingForAgent  = "waiting_for_agent"
+)
+
+var defaults = map[string]viewmodel.Text{
+	InvalidOption: {
+		"id": "Mohon untuk menjawab pilihan layanan hanya dalam format angka (misal: ketik '1'), sesuai dengan pilihan yang disediakan. Terima kasih",
+		"en": "Please answer with the number of one of the options provided (e.g. type '1'). Thank you",
+	},
+	InvalidInput: {
+		"id": "Mohon maaf, format jawaban Anda tidak sesuai. Silakan coba lagi",
+		"en": "Sorry, your answer is not in the expected format. Please try again",
+	},
+}
+
+func Get(key string) viewmodel.Text {
+	if value, exist := os.LookupEnv(strings.ToUpper(key) + "_WORDING"); exist {
+		if strings.HasPrefix(strings.TrimSpace(value), "{") {
+			var text viewmodel.Text
+			if err := json.Unmarshal([]byte(value), &text); err == nil {
+				return text
+			}
+		}
+		return viewmodel.NewText(value)
+	}
+
+	return defaults[key]
+}
+
+func FallbackLocale() string {
+	if locale := os.Getenv("FALLBACK_LOCALE"); len(locale) > 0 {
+		return locale
+	}
+
+	return "id"
+}
+
+func DefaultLocale(channelID int) string {
+	if locale, exist := os.LookupEnv(strconv.Itoa(channelID) + "_DEFAULT_LOCALE"); exist {
+		return locale
+	}
+
+	if locale, exist := os.LookupEnv("DEFAULT_LOCALE"); exist {
+		return locale
+	}
+
+	return FallbackLocale()
+}